@@ -59,9 +59,28 @@ type Logger struct {
 }
 
 type LoggerKafka struct {
-	Disable bool     // 是否禁用kafka日志输出
-	Topic   string   // kafka topic
-	Brokers []string // kafka brokers
+	Disable  bool       // 是否禁用kafka日志输出
+	Topic    string     // kafka topic
+	Brokers  []string   // kafka brokers
+	ClientID string     // kafka 客户端标识
+	SASL     *KafkaSASL // SASL 认证，为空时不认证
+	TLS      *KafkaTLS  // TLS 配置，为空时不使用 TLS
+}
+
+// KafkaSASL Kafka SASL 认证配置
+type KafkaSASL struct {
+	Mechanism string // 认证方式: PLAIN / SCRAM-SHA-256 / SCRAM-SHA-512
+	Username  string
+	Password  string
+}
+
+// KafkaTLS Kafka TLS 配置
+type KafkaTLS struct {
+	CAFile             string // CA 证书文件，为空时使用系统证书
+	CertFile           string // 客户端证书文件，双向认证时使用
+	KeyFile            string // 客户端私钥文件，双向认证时使用
+	ServerName         string // 校验的服务端名称
+	InsecureSkipVerify bool   // 跳过服务端证书校验
 }
 
 func (l *Logger) IsVaild() (vaild bool) {
@@ -0,0 +1,69 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package entx
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+)
+
+// 事务发件箱字段
+const (
+	OutboxFieldTopic       = "topic"
+	OutboxFieldKey         = "message_key"
+	OutboxFieldPayload     = "payload"
+	OutboxFieldProperties  = "properties"
+	OutboxFieldAttempts    = "attempts"
+	OutboxFieldLastError   = "last_error"
+	OutboxFieldAvailableAt = "available_at"
+	OutboxFieldSentAt      = "sent_at"
+	OutboxFieldFailedAt    = "failed_at"
+	OutboxFieldCreatedAt   = "created_at"
+)
+
+// OutboxMixin 事务发件箱混入，消息与业务数据在同一事务中写入，由 outbox.Relay 按 ID 顺序发送
+// 使用默认的自增 ID 保证消息顺序
+//
+// 使用示例:
+//
+//	type Outbox struct {
+//	    ent.Schema
+//	}
+//
+//	func (Outbox) Mixin() []ent.Mixin {
+//	    return []ent.Mixin{entx.OutboxMixin{}}
+//	}
+//
+//	tx.Outbox.Create().SetTopic("orders").SetMessageKey(order.ID).SetPayload(b).Exec(ctx)
+type OutboxMixin struct {
+	mixin.Schema
+}
+
+// Fields 定义发件箱字段
+func (OutboxMixin) Fields() []ent.Field {
+	return []ent.Field{
+		field.String(OutboxFieldTopic).NotEmpty().Immutable().Comment("Topic"),
+		field.String(OutboxFieldKey).Optional().Immutable().Comment("消息 Key，相同 Key 的消息按顺序发送"),
+		field.Bytes(OutboxFieldPayload).Immutable().Comment("消息内容"),
+		field.JSON(OutboxFieldProperties, map[string]string{}).Optional().Immutable().Comment("消息属性"),
+		field.Int(OutboxFieldAttempts).Default(0).Comment("发送失败次数"),
+		field.Text(OutboxFieldLastError).Optional().Comment("最后一次发送失败原因"),
+		field.Time(OutboxFieldAvailableAt).Default(time.Now).Comment("可发送时间，发送失败后延迟重试"),
+		field.Time(OutboxFieldSentAt).Optional().Nillable().Comment("发送时间"),
+		field.Time(OutboxFieldFailedAt).Optional().Nillable().Comment("超过最大重试次数后放弃发送的时间"),
+		field.Time(OutboxFieldCreatedAt).Immutable().Default(time.Now),
+	}
+}
+
+// Indexes 定义发件箱索引
+func (OutboxMixin) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields(OutboxFieldSentAt, OutboxFieldFailedAt),
+	}
+}
@@ -6,9 +6,12 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"os"
 
 	"github.com/segmentio/kafka-go"
 
+	"nexis.run/nexa/kit/configure"
 	"nexis.run/nexa/pkg/clara"
 )
 
@@ -16,9 +19,54 @@ type KafkaWriter struct {
 	*clara.Writer
 }
 
-func NewKafkaWriter(brokers []string, topic string) *KafkaWriter {
+// NewKafkaWriter 创建日志 Writer，opts 为客户端配置选项，如 SASL 认证和 TLS 配置
+func NewKafkaWriter(brokers []string, topic string, opts ...clara.ClientOption) *KafkaWriter {
+	options := []clara.Option{clara.WithCompletion(reportKafkaError)}
+	for _, opt := range opts {
+		options = append(options, opt)
+	}
 	return &KafkaWriter{
-		Writer: clara.NewWriter(brokers, topic),
+		Writer: clara.NewWriter(brokers, topic, options...),
+	}
+}
+
+// kafkaOptions 根据配置生成客户端配置选项，提前校验 SASL 认证方式和 TLS 证书文件
+func kafkaOptions(cfg *configure.LoggerKafka) ([]clara.ClientOption, error) {
+	var opts []clara.ClientOption
+
+	if cfg.ClientID != "" {
+		opts = append(opts, clara.WithClientID(cfg.ClientID))
+	}
+
+	if cfg.SASL != nil {
+		mechanism, err := clara.NewSASLMechanism(cfg.SASL.Mechanism, cfg.SASL.Username, cfg.SASL.Password)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, clara.WithSASL(mechanism))
+	}
+
+	if cfg.TLS != nil {
+		tlsConfig, err := clara.LoadTLSConfig(clara.TLSFiles{
+			CAFile:             cfg.TLS.CAFile,
+			CertFile:           cfg.TLS.CertFile,
+			KeyFile:            cfg.TLS.KeyFile,
+			ServerName:         cfg.TLS.ServerName,
+			InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, clara.WithTLS(tlsConfig))
+	}
+
+	return opts, nil
+}
+
+// reportKafkaError 异步发送失败时输出到标准错误，不能使用 zap 以免循环写入 Kafka
+func reportKafkaError(messages []kafka.Message, err error) {
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "[Logger] 发送 %d 条日志到 Kafka 失败: %v\n", len(messages), err)
 	}
 }
 
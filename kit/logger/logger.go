@@ -5,12 +5,14 @@
 package logger
 
 import (
+	"fmt"
 	"os"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"nexis.run/nexa/kit/configure"
+	"nexis.run/nexa/pkg/clara"
 )
 
 func Setup(cfg *configure.Logger) {
@@ -23,8 +25,24 @@ func Setup(cfg *configure.Logger) {
 	// 配置编码器 - 明确区分控制台和Kafka的编码器
 	consoleEncoder := ConsoleEncoder()
 
+	// 判断是否需要输出到Kafka
+	shouldLogToKafka := cfg.Kafka != nil && len(cfg.Kafka.Brokers) > 0 && !cfg.Kafka.Disable
+
+	// Kafka 认证配置错误时输出到标准错误，并改为输出到控制台以免丢失日志
+	var (
+		kafkaOpts []clara.ClientOption
+		kafkaErr  error
+	)
+	if shouldLogToKafka {
+		kafkaOpts, kafkaErr = kafkaOptions(cfg.Kafka)
+		if kafkaErr != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "[Logger] Kafka 配置错误，日志改为输出到控制台: %v\n", kafkaErr)
+			shouldLogToKafka = false
+		}
+	}
+
 	// 判断是否需要输出到控制台
-	shouldLogToConsole := cfg.Stdout || (cfg.Kafka == nil)
+	shouldLogToConsole := cfg.Stdout || (cfg.Kafka == nil) || kafkaErr != nil
 	if shouldLogToConsole {
 		consoleCore := zapcore.NewCore(
 			consoleEncoder,
@@ -34,14 +52,13 @@ func Setup(cfg *configure.Logger) {
 		cores = append(cores, consoleCore)
 	}
 
-	// 判断是否需要输出到Kafka
-	if cfg.Kafka != nil && len(cfg.Kafka.Brokers) > 0 && !cfg.Kafka.Disable {
+	if shouldLogToKafka {
 		// Kafka输出配置使用JSON格式 - 明确使用不同的配置
 		kafkaEncoderConfig := zap.NewProductionEncoderConfig()
 		kafkaEncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 		kafkaEncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
 		kafkaEncoder := zapcore.NewJSONEncoder(kafkaEncoderConfig)
-		kafkaWriter := NewKafkaWriter(cfg.Kafka.Brokers, cfg.Kafka.Topic)
+		kafkaWriter := NewKafkaWriter(cfg.Kafka.Brokers, cfg.Kafka.Topic, kafkaOpts...)
 
 		// 确保Kafka core只处理JSON格式的日志
 		kafkaCore := zapcore.NewCore(
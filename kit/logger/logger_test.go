@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 
 	"nexis.run/nexa/kit/configure"
+	"nexis.run/nexa/pkg/clara"
 )
 
 func TestLogger(t *testing.T) {
@@ -40,3 +41,25 @@ func TestLogger(t *testing.T) {
 
 	zap.L().Info("KAFKA test")
 }
+
+func TestKafkaOptions(t *testing.T) {
+	opts, err := kafkaOptions(&configure.LoggerKafka{
+		ClientID: "logger",
+		SASL:     &configure.KafkaSASL{Mechanism: "SCRAM-SHA-512", Username: "user", Password: "pass"},
+	})
+	require.NoError(t, err)
+
+	var cfg clara.Config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	require.Equal(t, "logger", cfg.ClientID)
+	require.Equal(t, clara.SASLScramSHA512, cfg.SASL.Name())
+	require.Nil(t, cfg.TLS)
+
+	_, err = kafkaOptions(&configure.LoggerKafka{SASL: &configure.KafkaSASL{Mechanism: "OAUTHBEARER"}})
+	require.ErrorIs(t, err, clara.ErrUnsupportedSASL)
+
+	_, err = kafkaOptions(&configure.LoggerKafka{TLS: &configure.KafkaTLS{CAFile: "missing.pem"}})
+	require.ErrorIs(t, err, clara.ErrInvalidTLS)
+}
@@ -0,0 +1,138 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// SASL 认证方式
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+var (
+	ErrUnsupportedSASL = errors.New("不支持的 SASL 认证方式")
+	ErrInvalidTLS      = errors.New("TLS 配置错误")
+)
+
+// tlsConfigs 已加载的 TLS 配置，相同文件返回同一个 *tls.Config 以便共享客户端
+var tlsConfigs sync.Map // map[TLSFiles]*tls.Config
+
+// NewSASLMechanism 根据认证方式名称创建 SASL 认证，名称不区分大小写
+func NewSASLMechanism(mechanism, username, password string) (sasl.Mechanism, error) {
+	switch strings.ToUpper(mechanism) {
+	case SASLPlain:
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case SASLScramSHA256:
+		return scramMechanism{algorithm: scram.SHA256, username: username, password: password}, nil
+	case SASLScramSHA512:
+		return scramMechanism{algorithm: scram.SHA512, username: username, password: password}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSASL, mechanism)
+	}
+}
+
+// scramMechanism SCRAM 认证
+// kafka-go 的 SCRAM 认证为指针类型，相同账号每次创建的认证均不相同，无法共享客户端，因此在每次认证时创建
+type scramMechanism struct {
+	algorithm scram.Algorithm
+	username  string
+	password  string
+}
+
+func (m scramMechanism) Name() string {
+	return m.algorithm.Name()
+}
+
+func (m scramMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	mechanism, err := scram.Mechanism(m.algorithm, m.username, m.password)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mechanism.Start(ctx)
+}
+
+// WithSASLPlain 使用 SASL PLAIN 认证，明文传输密码，需配合 TLS 使用
+func WithSASLPlain(username, password string) ClientOption {
+	return WithSASL(plain.Mechanism{Username: username, Password: password})
+}
+
+// WithSASLScramSHA256 使用 SASL SCRAM-SHA-256 认证
+func WithSASLScramSHA256(username, password string) ClientOption {
+	return WithSASL(scramMechanism{algorithm: scram.SHA256, username: username, password: password})
+}
+
+// WithSASLScramSHA512 使用 SASL SCRAM-SHA-512 认证
+func WithSASLScramSHA512(username, password string) ClientOption {
+	return WithSASL(scramMechanism{algorithm: scram.SHA512, username: username, password: password})
+}
+
+// TLSFiles 从文件加载的 TLS 配置
+type TLSFiles struct {
+	CAFile             string // CA 证书，为空时使用系统证书
+	CertFile           string // 客户端证书，双向认证时与 KeyFile 同时设置
+	KeyFile            string // 客户端私钥
+	ServerName         string // 校验的服务端名称，为空时使用 broker 地址
+	InsecureSkipVerify bool   // 跳过服务端证书校验，仅用于测试
+}
+
+// LoadTLSConfig 从文件加载 TLS 配置，配合 WithTLS 使用，配置错误时在创建客户端前返回
+// 加载成功的配置按 files 缓存，相同文件返回同一个 *tls.Config 以便共享客户端，证书文件更新后需重启进程生效
+//
+// 使用示例:
+//
+//	cfg, err := clara.LoadTLSConfig(clara.TLSFiles{CAFile: "ca.pem"})
+//	if err != nil {
+//	    return err
+//	}
+//	w := clara.NewWriter(brokers, "logs", clara.WithTLS(cfg))
+func LoadTLSConfig(files TLSFiles) (*tls.Config, error) {
+	if cfg, ok := tlsConfigs.Load(files); ok {
+		return cfg.(*tls.Config), nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         files.ServerName,
+		InsecureSkipVerify: files.InsecureSkipVerify, //nolint:gosec
+	}
+
+	if files.CAFile != "" {
+		b, err := os.ReadFile(files.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 读取 CA 证书失败: %w", ErrInvalidTLS, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("%w: CA 证书无效: %s", ErrInvalidTLS, files.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if files.CertFile != "" || files.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(files.CertFile, files.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 加载客户端证书失败: %w", ErrInvalidTLS, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	actual, _ := tlsConfigs.LoadOrStore(files, cfg)
+	return actual.(*tls.Config), nil
+}
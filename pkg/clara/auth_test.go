@@ -0,0 +1,128 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate 生成自签名证书并写入临时目录，返回证书和私钥文件路径
+func writeCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "clara"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return
+}
+
+func TestNewSASLMechanism(t *testing.T) {
+	mechanism, err := NewSASLMechanism("plain", "user", "pass")
+	require.NoError(t, err)
+	require.Equal(t, plain.Mechanism{Username: "user", Password: "pass"}, mechanism)
+
+	for _, name := range []string{SASLScramSHA256, SASLScramSHA512} {
+		mechanism, err = NewSASLMechanism(name, "user", "pass")
+		require.NoError(t, err)
+		require.Equal(t, name, mechanism.Name())
+
+		_, first, err := mechanism.Start(context.Background())
+		require.NoError(t, err)
+		require.Contains(t, string(first), "n=user")
+	}
+
+	_, err = NewSASLMechanism("GSSAPI", "user", "pass")
+	require.ErrorIs(t, err, ErrUnsupportedSASL)
+
+	// 相同的 SCRAM 认证共享同一个客户端
+	a := New([]string{"127.0.0.1:9092"}, WithClientID("auth"), WithSASLScramSHA512("user", "pass"))
+	defer func() {
+		_ = a.Close()
+	}()
+	require.Same(t, a, New([]string{"127.0.0.1:9092"}, WithClientID("auth"), WithSASLScramSHA512("user", "pass")))
+	require.NotSame(t, a, New([]string{"127.0.0.1:9092"}, WithClientID("auth"), WithSASLScramSHA256("user", "pass")))
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	certFile, keyFile := writeCertificate(t)
+
+	files := TLSFiles{CAFile: certFile, CertFile: certFile, KeyFile: keyFile, ServerName: "kafka"}
+	cfg, err := LoadTLSConfig(files)
+	require.NoError(t, err)
+	require.Len(t, cfg.Certificates, 1)
+	require.NotNil(t, cfg.RootCAs)
+	require.Equal(t, "kafka", cfg.ServerName)
+
+	// 相同文件返回同一个配置
+	same, err := LoadTLSConfig(files)
+	require.NoError(t, err)
+	require.Same(t, cfg, same)
+
+	_, err = LoadTLSConfig(TLSFiles{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	require.ErrorIs(t, err, ErrInvalidTLS)
+
+	_, err = LoadTLSConfig(TLSFiles{CAFile: keyFile})
+	require.ErrorIs(t, err, ErrInvalidTLS)
+
+	// 仅设置证书未设置私钥
+	_, err = LoadTLSConfig(TLSFiles{CertFile: certFile})
+	require.ErrorIs(t, err, ErrInvalidTLS)
+
+	// 相同文件加载的配置共享客户端
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("tls"), WithTLS(cfg))
+	defer func() {
+		_ = c.Close()
+	}()
+	require.Same(t, c, New([]string{"127.0.0.1:9092"}, WithClientID("tls"), WithTLS(same)))
+}
+
+func TestClientOptions(t *testing.T) {
+	w := NewWriter([]string{"127.0.0.1:9092"}, "logs", WithSync(), WithClientID("client-options"), WithSASLPlain("user", "pass"))
+	defer func() {
+		_ = w.clara.Close()
+	}()
+
+	require.False(t, w.async)
+	require.Equal(t, "client-options", w.clara.Config().ClientID)
+	require.Equal(t, plain.Mechanism{Username: "user", Password: "pass"}, w.clara.Config().SASL)
+	require.Equal(t, "client-options", w.writer.Transport.(*kafka.Transport).ClientID)
+
+	// Reader 与 Writer 使用相同配置时共享客户端
+	r := NewReader([]string{"127.0.0.1:9092"}, "logs", "group", WithClientID("client-options"), WithSASLPlain("user", "pass"))
+	require.Same(t, w.clara, r.clara)
+	require.Equal(t, "client-options", r.clara.dialer.ClientID)
+}
@@ -5,28 +5,253 @@
 package clara
 
 import (
+	"cmp"
+	"crypto/tls"
+	"errors"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
-	cmap "github.com/orcaman/concurrent-map/v2"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
 )
 
-var instances = cmap.New[*Clara]()
+var (
+	registry  sync.Mutex
+	instances = make(map[clientKey]*Clara)
+)
+
+var ErrClientClosed = errors.New("kafka 客户端已关闭")
+
+// Config 客户端配置，相同配置的客户端共享同一个 Clara 实例
+type Config struct {
+	Brokers     []string
+	ClientID    string         // 客户端标识
+	SASL        sasl.Mechanism // SASL 认证，需为可比较类型，kafka-go 内置的认证方式均满足
+	TLS         *tls.Config    // TLS 配置，按指针区分
+	DialTimeout time.Duration  // 建立连接超时时间，包含 TLS 握手和 SASL 认证，默认 5s
+	IdleTimeout time.Duration  // 空闲连接保留时间，默认 30s
+	MetadataTTL time.Duration  // 元数据缓存时间，默认 6s
+}
+
+// ClientOption 客户端配置选项
+// 同时可作为 NewWriter 的 Option 使用，创建 Writer 时用于获取客户端
+type ClientOption func(*Config)
+
+// apply 客户端配置选项在获取客户端时生效，对 Writer 不生效
+func (ClientOption) apply(*Writer) {}
+
+// clientOptions 从 Writer 选项中取出客户端配置选项
+func clientOptions(opts []Option) (options []ClientOption) {
+	for _, opt := range opts {
+		if o, ok := opt.(ClientOption); ok {
+			options = append(options, o)
+		}
+	}
+	return
+}
 
+// WithClientID 设置客户端标识
+func WithClientID(id string) ClientOption {
+	return func(c *Config) {
+		c.ClientID = id
+	}
+}
+
+// WithSASL 设置 SASL 认证
+func WithSASL(mechanism sasl.Mechanism) ClientOption {
+	return func(c *Config) {
+		c.SASL = mechanism
+	}
+}
+
+// WithTLS 设置 TLS 配置，相同配置需复用同一个 *tls.Config 才能共享客户端
+func WithTLS(cfg *tls.Config) ClientOption {
+	return func(c *Config) {
+		c.TLS = cfg
+	}
+}
+
+// WithDialTimeout 设置建立连接超时时间
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(c *Config) {
+		c.DialTimeout = timeout
+	}
+}
+
+// WithIdleTimeout 设置空闲连接保留时间
+func WithIdleTimeout(timeout time.Duration) ClientOption {
+	return func(c *Config) {
+		c.IdleTimeout = timeout
+	}
+}
+
+// WithMetadataTTL 设置元数据缓存时间
+func WithMetadataTTL(ttl time.Duration) ClientOption {
+	return func(c *Config) {
+		c.MetadataTTL = ttl
+	}
+}
+
+// clientKey 客户端注册表 key
+type clientKey struct {
+	brokers     string
+	clientID    string
+	sasl        sasl.Mechanism
+	tls         *tls.Config
+	dialTimeout time.Duration
+	idleTimeout time.Duration
+	metadataTTL time.Duration
+}
+
+// key 返回配置对应的注册表 key，broker 顺序不影响 key，SASL 不可比较时返回 false
+func (c Config) key() (clientKey, bool) {
+	brokers := slices.Clone(c.Brokers)
+	slices.Sort(brokers)
+
+	if c.SASL != nil && !reflect.TypeOf(c.SASL).Comparable() {
+		return clientKey{}, false
+	}
+
+	return clientKey{
+		brokers:     strings.Join(slices.Compact(brokers), ","),
+		clientID:    c.ClientID,
+		sasl:        c.SASL,
+		tls:         c.TLS,
+		dialTimeout: c.DialTimeout,
+		idleTimeout: c.IdleTimeout,
+		metadataTTL: c.MetadataTTL,
+	}, true
+}
+
+// Clara Kafka 客户端，管理共享的连接配置以及由其创建的 Writer 和 Reader
 type Clara struct {
-	brokers []string
-	writers cmap.ConcurrentMap[string, *Writer]
+	config    Config
+	transport *kafka.Transport
+	dialer    *kafka.Dialer
+
+	mu      sync.Mutex
+	writers map[string]*Writer
+	readers map[*Reader]struct{}
+	closed  bool
 }
 
-func New(brokers []string) *Clara {
-	key := strings.Join(brokers, ",")
-	if instance, exists := instances.Get(key); exists {
-		return instance
+// New 获取 broker 和配置对应的客户端，相同配置返回同一个实例
+//
+// 使用示例:
+//
+//	c := clara.New(brokers, clara.WithClientID("order-service"), clara.WithSASL(plain.Mechanism{Username: "u", Password: "p"}))
+//	defer c.Close()
+//	w, err := c.Writer("orders")
+func New(brokers []string, opts ...ClientOption) *Clara {
+	cfg := Config{Brokers: brokers}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return Get(cfg)
+}
+
+// Get 获取配置对应的客户端，相同配置返回同一个实例
+func Get(cfg Config) *Clara {
+	key, ok := cfg.key()
+	if !ok {
+		return newClara(cfg)
 	}
 
-	c := &Clara{
-		brokers: brokers,
-		writers: cmap.New[*Writer](),
+	registry.Lock()
+	defer registry.Unlock()
+
+	if instance, exists := instances[key]; exists {
+		return instance
 	}
 
+	c := newClara(cfg)
+	instances[key] = c
 	return c
 }
+
+// newClara 创建客户端
+func newClara(cfg Config) *Clara {
+	cfg.Brokers = slices.Clone(cfg.Brokers)
+	return &Clara{
+		config: cfg,
+		transport: &kafka.Transport{
+			ClientID:    cfg.ClientID,
+			SASL:        cfg.SASL,
+			TLS:         cfg.TLS,
+			DialTimeout: cfg.DialTimeout,
+			IdleTimeout: cfg.IdleTimeout,
+			MetadataTTL: cfg.MetadataTTL,
+		},
+		dialer: &kafka.Dialer{
+			ClientID:      cfg.ClientID,
+			SASLMechanism: cfg.SASL,
+			TLS:           cfg.TLS,
+			Timeout:       cmp.Or(cfg.DialTimeout, 10*time.Second),
+			DualStack:     true,
+		},
+		writers: make(map[string]*Writer),
+		readers: make(map[*Reader]struct{}),
+	}
+}
+
+// Config 返回客户端配置
+func (c *Clara) Config() Config {
+	return c.config
+}
+
+// Close 关闭客户端创建的所有 Writer 和 Reader，并从注册表中移除，重复关闭时直接返回
+// 关闭后再次调用 New 会创建新的客户端，在已关闭的客户端上创建 Writer 和 Reader 返回 ErrClientClosed
+func (c *Clara) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	writers := c.writers
+	readers := c.readers
+	c.writers = make(map[string]*Writer)
+	c.readers = make(map[*Reader]struct{})
+	c.mu.Unlock()
+
+	if key, ok := c.config.key(); ok {
+		registry.Lock()
+		if instances[key] == c {
+			delete(instances, key)
+		}
+		registry.Unlock()
+	}
+
+	var errs []error
+	for _, w := range writers {
+		errs = append(errs, w.close())
+	}
+	for r := range readers {
+		errs = append(errs, r.close())
+	}
+
+	c.transport.CloseIdleConnections()
+
+	return errors.Join(errs...)
+}
+
+// removeWriter 移除已关闭的 Writer
+func (c *Clara) removeWriter(w *Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writers[w.topic] == w {
+		delete(c.writers, w.topic)
+	}
+}
+
+// removeReader 移除已关闭的 Reader
+func (c *Clara) removeReader(r *Reader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.readers, r)
+}
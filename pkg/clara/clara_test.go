@@ -0,0 +1,84 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	brokers := []string{"127.0.0.1:9092", "127.0.0.1:9093"}
+	mechanism := plain.Mechanism{Username: "nexa", Password: "secret"}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	c := New(brokers, WithClientID("order"), WithSASL(mechanism), WithTLS(tlsConfig), WithDialTimeout(time.Second))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	// broker 顺序不影响实例复用
+	require.Same(t, c, New([]string{"127.0.0.1:9093", "127.0.0.1:9092"}, WithClientID("order"), WithSASL(mechanism), WithTLS(tlsConfig), WithDialTimeout(time.Second)))
+	require.Same(t, c, Get(c.Config()))
+
+	// 配置不同时创建新的实例
+	require.NotSame(t, c, New(brokers, WithClientID("order")))
+	require.NotSame(t, c, New(brokers, WithClientID("order"), WithSASL(mechanism), WithTLS(&tls.Config{}), WithDialTimeout(time.Second)))
+
+	require.Equal(t, "order", c.transport.ClientID)
+	require.Equal(t, mechanism, c.dialer.SASLMechanism)
+	require.Equal(t, time.Second, c.dialer.Timeout)
+}
+
+func TestWriterDedupe(t *testing.T) {
+	brokers := []string{"127.0.0.1:9092"}
+	w := NewWriter(brokers, "logs", WithRetries(5))
+	require.Same(t, w, NewWriter(brokers, "logs"))
+	require.Equal(t, 5, w.retries)
+	require.Same(t, New(brokers).transport, w.writer.Transport)
+
+	// 关闭后重新创建
+	require.NoError(t, w.Close())
+	next := NewWriter(brokers, "logs")
+	require.NotSame(t, w, next)
+	require.NoError(t, next.Close())
+}
+
+func TestClose(t *testing.T) {
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("close"))
+	w, err := c.Writer("logs")
+	require.NoError(t, err)
+	r, err := c.Reader("logs", "")
+	require.NoError(t, err)
+	require.Same(t, c.dialer, r.reader.Config().Dialer)
+
+	require.NoError(t, c.Close())
+	require.Empty(t, c.writers)
+	require.Empty(t, c.readers)
+
+	// 已关闭的客户端不能再创建 Writer 和 Reader
+	_, err = c.Writer("logs")
+	require.ErrorIs(t, err, ErrClientClosed)
+	_, err = c.Reader("logs", "")
+	require.ErrorIs(t, err, ErrClientClosed)
+
+	// 关闭后获取新的实例
+	next := New([]string{"127.0.0.1:9092"}, WithClientID("close"))
+	require.NotSame(t, c, next)
+	nw, err := next.Writer("logs")
+	require.NoError(t, err)
+	require.NotSame(t, w, nw)
+	require.Same(t, nw, NewWriter([]string{"127.0.0.1:9092"}, "logs", WithClientID("close")))
+	require.NoError(t, next.Close())
+
+	// 重复关闭不报错
+	require.NoError(t, c.Close())
+	require.NoError(t, w.Close())
+	require.NoError(t, r.Close())
+}
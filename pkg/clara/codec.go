@@ -0,0 +1,84 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"google.golang.org/protobuf/proto"
+)
+
+// 消息 header
+const (
+	HeaderContentType   = "content-type"
+	HeaderSchemaVersion = "schema-version"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+var (
+	ErrNotProtoMessage     = errors.New("消息类型未实现 proto.Message")
+	ErrContentTypeMismatch = errors.New("消息内容类型不匹配")
+)
+
+// Codec 消息编解码器
+type Codec interface {
+	// ContentType 返回编码后的内容类型，发送时写入 HeaderContentType
+	ContentType() string
+
+	// Marshal 编码消息
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal 解码消息
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	_ Codec = JSONCodec{}
+	_ Codec = ProtoCodec{}
+)
+
+// JSONCodec 使用 sonic 进行 JSON 编解码
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return sonic.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return sonic.Unmarshal(data, v)
+}
+
+// ProtoCodec protobuf 编解码，消息类型需要实现 proto.Message
+type ProtoCodec struct{}
+
+func (ProtoCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (ProtoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+	}
+	return proto.Marshal(m)
+}
+
+func (ProtoCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrNotProtoMessage, v)
+	}
+	return proto.Unmarshal(data, m)
+}
@@ -0,0 +1,388 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// 死信消息 header
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderError             = "x-error"
+	HeaderAttempts          = "x-attempts"
+)
+
+const (
+	DefaultMaxAttempts = 3                      // 默认最大处理次数
+	DefaultMinBackoff  = 100 * time.Millisecond // 默认首次重试延迟
+	DefaultMaxBackoff  = 5 * time.Second        // 默认最大重试延迟
+)
+
+var ErrHandleFailed = errors.New("kafka 消息处理失败")
+
+// Handler 消息处理函数，返回 nil 后提交 offset
+type Handler func(ctx context.Context, message kafka.Message) error
+
+// ConsumeOptions 消费配置
+type ConsumeOptions struct {
+	concurrency    int
+	commitInterval time.Duration
+	maxAttempts    int
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+	deadLetter     string
+	deadLetterOut  messageWriter // 死信写入，默认为 kafka.Writer
+}
+
+// ConsumeOption 消费选项
+type ConsumeOption func(*ConsumeOptions)
+
+// WithConcurrency 设置 worker 数量，默认 1
+// 同一分区的消息由同一 worker 按顺序处理，worker 数量超过分区数时多余的 worker 空闲
+func WithConcurrency(n int) ConsumeOption {
+	return func(o *ConsumeOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithCommitInterval 设置批量提交 offset 的间隔，默认 0 表示每条消息处理完成后立即提交
+// 批量提交可减少 broker 请求，进程异常退出时最多重复处理一个间隔内的消息
+func WithCommitInterval(interval time.Duration) ConsumeOption {
+	return func(o *ConsumeOptions) {
+		o.commitInterval = interval
+	}
+}
+
+// WithMaxAttempts 设置单条消息的最大处理次数，默认 3
+func WithMaxAttempts(n int) ConsumeOption {
+	return func(o *ConsumeOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithRetryBackoff 设置处理失败后的指数退避重试延迟，默认 100ms ~ 5s
+func WithRetryBackoff(minBackoff, maxBackoff time.Duration) ConsumeOption {
+	return func(o *ConsumeOptions) {
+		o.minBackoff = minBackoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// WithDeadLetter 设置死信 Topic，超过最大处理次数的消息发送到死信 Topic 后提交 offset
+// 死信消息保留原消息的 Key、Value 和 Header，并附加原 Topic、分区、offset、错误和处理次数，
+// 可由其他 Reader 消费死信 Topic 实现延迟重试；Consume 使用独立的同步 Writer 发送死信，退出时关闭
+// 未设置时超过最大处理次数的消息不提交，Consume 返回 ErrHandleFailed
+func WithDeadLetter(topic string) ConsumeOption {
+	return func(o *ConsumeOptions) {
+		o.deadLetter = topic
+	}
+}
+
+// withDeadLetterWriter 使用 mw 代替 kafka.Writer 写入死信
+func withDeadLetterWriter(mw messageWriter) ConsumeOption {
+	return func(o *ConsumeOptions) {
+		o.deadLetterOut = mw
+	}
+}
+
+// backoff 返回第 attempt 次失败后的重试延迟
+func (o *ConsumeOptions) backoff(attempt int) time.Duration {
+	d := o.minBackoff
+	for i := 1; i < attempt && d < o.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.maxBackoff)
+}
+
+// messageSource 消息来源，默认为 kafka.Reader
+type messageSource interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// consumer 一次 Consume 调用的运行状态
+type consumer struct {
+	source     messageSource
+	handler    Handler
+	options    *ConsumeOptions
+	deadLetter *Writer // 死信 Writer，未设置死信 Topic 时为 nil
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	pending map[int]kafka.Message // 每个分区待提交的最新消息
+}
+
+// Consume 消费消息，消息处理成功后提交 offset，阻塞直到 context 取消或出现无法恢复的错误
+// Reader 必须设置 groupID
+//
+// 使用示例:
+//
+//	r := clara.NewReader(brokers, "orders", "order-service")
+//	err := r.Consume(ctx, func(ctx context.Context, message kafka.Message) error {
+//	    return handle(ctx, message)
+//	}, clara.WithConcurrency(4), clara.WithCommitInterval(time.Second), clara.WithDeadLetter("orders-dlq"))
+func (r *Reader) Consume(ctx context.Context, handler Handler, opts ...ConsumeOption) error {
+	options := &ConsumeOptions{
+		concurrency: 1,
+		maxAttempts: DefaultMaxAttempts,
+		minBackoff:  DefaultMinBackoff,
+		maxBackoff:  DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	options.concurrency = max(options.concurrency, 1)
+	options.maxAttempts = max(options.maxAttempts, 1)
+
+	c := &consumer{
+		source:  r.source,
+		handler: handler,
+		options: options,
+		pending: make(map[int]kafka.Message),
+	}
+	c.ctx, c.cancel = context.WithCancelCause(ctx)
+	defer c.cancel(nil)
+
+	// 死信必须确认写入后才能提交 offset，使用独立的同步 Writer，不受客户端缓存的同名异步 Writer 影响
+	if options.deadLetter != "" {
+		writerOpts := []Option{WithSync()}
+		if options.deadLetterOut != nil {
+			writerOpts = append(writerOpts, withMessageWriter(options.deadLetterOut))
+		}
+		c.deadLetter = r.clara.newWriter(options.deadLetter, writerOpts...)
+		defer func() {
+			_ = c.deadLetter.close()
+		}()
+	}
+
+	return c.run()
+}
+
+// run 拉取消息并按分区分发给 worker
+func (c *consumer) run() error {
+	var wg sync.WaitGroup
+	workers := make([]chan kafka.Message, c.options.concurrency)
+	for i := range workers {
+		workers[i] = make(chan kafka.Message)
+		wg.Add(1)
+		go func(ch <-chan kafka.Message) {
+			defer wg.Done()
+			for message := range ch {
+				c.process(message)
+			}
+		}(workers[i])
+	}
+
+	var committer sync.WaitGroup
+	if c.options.commitInterval > 0 {
+		committer.Add(1)
+		go func() {
+			defer committer.Done()
+			c.commitLoop()
+		}()
+	}
+
+	c.fetch(workers)
+
+	for _, ch := range workers {
+		close(ch)
+	}
+	wg.Wait()
+	committer.Wait()
+
+	// 提交已处理完成的消息
+	if err := c.commit(context.WithoutCancel(c.ctx)); err != nil {
+		c.cancel(err)
+	}
+
+	return context.Cause(c.ctx)
+}
+
+// fetch 拉取消息直到 context 取消
+func (c *consumer) fetch(workers []chan kafka.Message) {
+	for {
+		message, err := c.source.FetchMessage(c.ctx)
+		if err != nil {
+			if c.ctx.Err() == nil {
+				c.cancel(err)
+			}
+			return
+		}
+
+		select {
+		case workers[message.Partition%len(workers)] <- message:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// process 处理单条消息，失败时按退避策略重试，超过最大处理次数后发送到死信 Topic
+func (c *consumer) process(message kafka.Message) {
+	var (
+		err     error
+		attempt int
+	)
+	for attempt = 1; attempt <= c.options.maxAttempts; attempt++ {
+		err = c.handle(message)
+		if err == nil {
+			break
+		}
+
+		// 停止消费导致的失败不重试，未提交的消息重启后重新消费
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		zap.L().Warn("[Kafka Consumer] 消息处理失败",
+			zap.String("topic", message.Topic),
+			zap.Int("partition", message.Partition),
+			zap.Int64("offset", message.Offset),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+
+		// 解码失败重试也无法成功
+		var decodeErr *DecodeError
+		if attempt == c.options.maxAttempts || errors.As(err, &decodeErr) {
+			break
+		}
+
+		select {
+		case <-c.ctx.Done():
+			// 未处理完成的消息不提交，重启后重新消费
+			return
+		case <-time.After(c.options.backoff(attempt)):
+		}
+	}
+
+	if err != nil {
+		if err = c.sendDeadLetter(message, attempt, err); err != nil {
+			c.cancel(err)
+			return
+		}
+	}
+
+	c.done(message)
+}
+
+// handle 调用处理函数，panic 按处理失败处理
+func (c *consumer) handle(message kafka.Message) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return c.handler(c.ctx, message)
+}
+
+// sendDeadLetter 将处理失败的消息发送到死信 Topic，未设置死信 Topic 时返回 ErrHandleFailed
+func (c *consumer) sendDeadLetter(message kafka.Message, attempts int, cause error) error {
+	if c.deadLetter == nil {
+		return fmt.Errorf("%w: topic=%s, partition=%d, offset=%d: %w", ErrHandleFailed, message.Topic, message.Partition, message.Offset, cause)
+	}
+
+	headers := append(message.Headers[:len(message.Headers):len(message.Headers)],
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(message.Topic)},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(message.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+	)
+
+	err := c.deadLetter.SendMessages(context.WithoutCancel(c.ctx), kafka.Message{
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("发送死信消息失败: topic=%s: %w", c.options.deadLetter, err)
+	}
+
+	zap.L().Error("[Kafka Consumer] 消息进入死信 Topic",
+		zap.String("topic", message.Topic),
+		zap.Int("partition", message.Partition),
+		zap.Int64("offset", message.Offset),
+		zap.String("dead_letter", c.options.deadLetter),
+		zap.Error(cause),
+	)
+	return nil
+}
+
+// done 记录处理完成的消息，未设置批量提交间隔时立即提交
+// 同一分区的消息由同一 worker 按顺序处理，提交最新的消息即提交之前的所有消息
+func (c *consumer) done(message kafka.Message) {
+	if c.options.commitInterval <= 0 {
+		if err := c.source.CommitMessages(c.ctx, message); err != nil && c.ctx.Err() == nil {
+			c.cancel(fmt.Errorf("提交 offset 失败: %w", err))
+		}
+		return
+	}
+
+	c.mu.Lock()
+	c.pending[message.Partition] = message
+	c.mu.Unlock()
+}
+
+// commitLoop 按间隔批量提交
+func (c *consumer) commitLoop() {
+	ticker := time.NewTicker(c.options.commitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.commit(c.ctx); err != nil && c.ctx.Err() == nil {
+				c.cancel(err)
+				return
+			}
+		}
+	}
+}
+
+// commit 提交各分区最新处理完成的消息，仅由批量提交的 goroutine 和退出时调用
+func (c *consumer) commit(ctx context.Context) error {
+	c.mu.Lock()
+	if len(c.pending) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	messages := make([]kafka.Message, 0, len(c.pending))
+	for partition, message := range c.pending {
+		messages = append(messages, message)
+		delete(c.pending, partition)
+	}
+	c.mu.Unlock()
+
+	err := c.source.CommitMessages(ctx, messages...)
+	if err != nil {
+		// 提交失败时放回待提交队列，已有更新的消息时保留更新的消息
+		c.mu.Lock()
+		for _, message := range messages {
+			if current, ok := c.pending[message.Partition]; !ok || current.Offset < message.Offset {
+				c.pending[message.Partition] = message
+			}
+		}
+		c.mu.Unlock()
+		return fmt.Errorf("提交 offset 失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSource 按顺序返回预设消息并记录提交的消息
+type stubSource struct {
+	messages chan kafka.Message
+
+	mu        sync.Mutex
+	committed map[int]int64 // 每个分区提交的最新 offset
+	commits   int
+}
+
+func newStubSource(messages ...kafka.Message) *stubSource {
+	s := &stubSource{messages: make(chan kafka.Message, len(messages)), committed: make(map[int]int64)}
+	for _, m := range messages {
+		s.messages <- m
+	}
+	return s
+}
+
+func (s *stubSource) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case m := <-s.messages:
+		return m, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (s *stubSource) CommitMessages(_ context.Context, messages ...kafka.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commits++
+	for _, m := range messages {
+		if m.Offset < s.committed[m.Partition] {
+			return errors.New("offset 回退")
+		}
+		s.committed[m.Partition] = m.Offset
+	}
+	return nil
+}
+
+func (s *stubSource) Close() error {
+	return nil
+}
+
+func (s *stubSource) offset(partition int) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.committed[partition]
+}
+
+// newStubReader 创建从 source 读取消息的 Reader
+func newStubReader(t *testing.T, c *Clara, source *stubSource) *Reader {
+	r, err := c.newReader("orders", "order-service", source)
+	require.NoError(t, err)
+	return r
+}
+
+// messages 生成 partitions 个分区、每个分区 n 条消息
+func messages(partitions, n int) (list []kafka.Message) {
+	for offset := range n {
+		for partition := range partitions {
+			list = append(list, kafka.Message{Topic: "orders", Partition: partition, Offset: int64(offset)})
+		}
+	}
+	return
+}
+
+func TestConsumeOrdered(t *testing.T) {
+	source := newStubSource(messages(4, 20)...)
+	r := newStubReader(t, New([]string{"127.0.0.1:9092"}), source)
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[int]int64)
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Consume(ctx, func(_ context.Context, m kafka.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			// 同一分区按顺序处理
+			if last, ok := seen[m.Partition]; ok {
+				require.Equal(t, last+1, m.Offset)
+			}
+			seen[m.Partition] = m.Offset
+			return nil
+		}, WithConcurrency(3))
+	}()
+
+	require.Eventually(t, func() bool {
+		for partition := range 4 {
+			if source.offset(partition) != 19 {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, 80, source.commits)
+}
+
+func TestConsumeCommitInterval(t *testing.T) {
+	source := newStubSource(messages(2, 10)...)
+	r := newStubReader(t, New([]string{"127.0.0.1:9092"}), source)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Consume(ctx, func(context.Context, kafka.Message) error {
+			return nil
+		}, WithConcurrency(2), WithCommitInterval(time.Hour))
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(source.messages) == 0
+	}, time.Second, 5*time.Millisecond)
+	require.Zero(t, source.commits)
+
+	// 退出时提交已处理完成的消息
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, 1, source.commits)
+	require.Equal(t, int64(9), source.offset(0))
+	require.Equal(t, int64(9), source.offset(1))
+}
+
+func TestConsumeDeadLetter(t *testing.T) {
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("consume-dead-letter"))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	// 客户端已有同名的异步 Writer 时不影响死信发送
+	async, err := c.Writer("orders-dlq")
+	require.NoError(t, err)
+	require.True(t, async.async)
+
+	dead := &stubWriter{}
+	source := newStubSource(
+		kafka.Message{Topic: "orders", Partition: 0, Offset: 1, Value: []byte("poison"), Headers: []kafka.Header{{Key: "trace", Value: []byte("t1")}}},
+		kafka.Message{Topic: "orders", Partition: 0, Offset: 2, Value: []byte("ok")},
+	)
+	r := newStubReader(t, c, source)
+
+	var attempts int
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Consume(ctx, func(_ context.Context, m kafka.Message) error {
+			if string(m.Value) == "poison" {
+				attempts++
+				panic("boom")
+			}
+			return nil
+		}, WithMaxAttempts(2), WithRetryBackoff(time.Millisecond, time.Millisecond), WithDeadLetter("orders-dlq"), withDeadLetterWriter(dead))
+	}()
+
+	require.Eventually(t, func() bool {
+		return source.offset(0) == 2
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	require.Equal(t, 2, attempts)
+	current, err := c.Writer("orders-dlq")
+	require.NoError(t, err)
+	require.Same(t, async, current)
+	letters := dead.written()
+	require.Len(t, letters, 1)
+	require.Equal(t, "poison", string(letters[0].Value))
+	require.Equal(t, 1, dead.closed)
+
+	headers := make(map[string]string)
+	for _, h := range letters[0].Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	require.Equal(t, map[string]string{
+		"trace":                 "t1",
+		HeaderOriginalTopic:     "orders",
+		HeaderOriginalPartition: "0",
+		HeaderOriginalOffset:    "1",
+		HeaderError:             "panic: boom",
+		HeaderAttempts:          "2",
+	}, headers)
+}
+
+func TestConsumeHandleFailed(t *testing.T) {
+	source := newStubSource(kafka.Message{Topic: "orders", Partition: 0, Offset: 1})
+	r := newStubReader(t, New([]string{"127.0.0.1:9092"}), source)
+
+	// 未设置死信 Topic 时不提交并返回错误
+	err := r.Consume(context.Background(), func(context.Context, kafka.Message) error {
+		return errors.New("boom")
+	}, WithMaxAttempts(1))
+	require.ErrorIs(t, err, ErrHandleFailed)
+	require.ErrorContains(t, err, "boom")
+	require.Zero(t, source.commits)
+}
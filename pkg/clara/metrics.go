@@ -0,0 +1,82 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics 发送指标收集接口
+type Metrics interface {
+	// ObserveDelivery 记录最终发送结果的消息数量，异步模式下在 broker 确认或重试失败后记录
+	ObserveDelivery(topic string, n int, err error)
+	// Retry 记录重试发送的消息数量
+	Retry(topic string, n int)
+}
+
+var _ Metrics = nopMetrics{}
+
+// nopMetrics 不收集任何指标
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveDelivery(string, int, error) {}
+func (nopMetrics) Retry(string, int)                  {}
+
+// resultLabel 返回结果标签
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+var _ Metrics = (*PrometheusMetrics)(nil)
+
+// PrometheusMetrics 基于 Prometheus 的指标收集
+//
+// 指标:
+//   - clara_messages_total{topic, result} 发送结果的消息数量
+//   - clara_retries_total{topic} 重试发送的消息数量
+type PrometheusMetrics struct {
+	messages *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics 创建 Prometheus 指标收集并注册到 registerer
+//
+// 使用示例:
+//
+//	metrics, err := clara.NewPrometheusMetrics(prometheus.DefaultRegisterer)
+//	w := clara.NewWriter(brokers, "logs", clara.WithMetrics(metrics))
+func NewPrometheusMetrics(registerer prometheus.Registerer) (*PrometheusMetrics, error) {
+	m := &PrometheusMetrics{
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clara",
+			Name:      "messages_total",
+			Help:      "Kafka 消息发送结果数量",
+		}, []string{"topic", "result"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "clara",
+			Name:      "retries_total",
+			Help:      "Kafka 消息重试发送数量",
+		}, []string{"topic"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.messages, m.retries} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *PrometheusMetrics) ObserveDelivery(topic string, n int, err error) {
+	m.messages.WithLabelValues(topic, resultLabel(err)).Add(float64(n))
+}
+
+func (m *PrometheusMetrics) Retry(topic string, n int) {
+	m.retries.WithLabelValues(topic).Add(float64(n))
+}
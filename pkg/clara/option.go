@@ -39,3 +39,38 @@ func WithRetryInterval(interval time.Duration) Option {
 		c.retryInterval = interval
 	})
 }
+
+// WithSync 使用同步发送，SendMessages 等待 broker 确认后返回
+func WithSync() Option {
+	return optionFunc(func(c *Writer) {
+		c.async = false
+	})
+}
+
+// WithCompletion 设置异步发送完成回调，同步模式下不生效
+func WithCompletion(fn Completion) Option {
+	return optionFunc(func(c *Writer) {
+		c.completion = fn
+	})
+}
+
+// WithErrors 设置异步发送失败的错误通道，错误类型为 *DeliveryError，通道已满时丢弃
+func WithErrors(ch chan<- error) Option {
+	return optionFunc(func(c *Writer) {
+		c.errors = ch
+	})
+}
+
+// withMessageWriter 使用 mw 代替 kafka.Writer 写入消息
+func withMessageWriter(mw messageWriter) Option {
+	return optionFunc(func(c *Writer) {
+		c.output = mw
+	})
+}
+
+// WithMetrics 设置发送指标收集
+func WithMetrics(metrics Metrics) Option {
+	return optionFunc(func(c *Writer) {
+		c.metrics = metrics
+	})
+}
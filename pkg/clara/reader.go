@@ -6,6 +6,7 @@ package clara
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -17,30 +18,65 @@ type Reader struct {
 
 	clara  *Clara
 	reader *kafka.Reader
+	source messageSource // Consume 的消息来源，默认为 reader
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
 type MessageListener func(message kafka.Message, err error) error
 
 var _ = NewReader
 
-// NewReader 创建一个新的Kafka Reader
-func NewReader(brokers []string, topic, groupID string) *Reader {
-	c := New(brokers)
+// NewReader 创建一个新的Kafka Reader，opts 为客户端配置选项，如 WithClientID、WithSASLPlain、WithTLS
+// 共享的客户端被并发关闭时使用新的客户端创建
+func NewReader(brokers []string, topic, groupID string, opts ...ClientOption) *Reader {
+	for {
+		// 已关闭的客户端已从注册表中移除，再次获取时创建新的客户端
+		if r, err := New(brokers, opts...).Reader(topic, groupID); err == nil {
+			return r
+		}
+	}
+}
+
+// Reader 创建一个新的 Kafka Reader，客户端关闭时一并关闭，客户端已关闭时返回 ErrClientClosed
+func (c *Clara) Reader(topic, groupID string) (*Reader, error) {
+	return c.newReader(topic, groupID, nil)
+}
+
+// newReader 创建并注册 Reader，source 为 nil 时从 broker 读取消息
+func (c *Clara) newReader(topic, groupID string, source messageSource) (*Reader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return &Reader{
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+
+	r := &Reader{
 		topic:   topic,
 		groupID: groupID,
 		clara:   c,
-		reader: kafka.NewReader(kafka.ReaderConfig{
-			Brokers:  c.brokers,
+		source:  source,
+	}
+
+	if r.source == nil {
+		r.reader = kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  c.config.Brokers,
 			Topic:    topic,
 			GroupID:  groupID,
+			Dialer:   c.dialer,
 			MaxBytes: 10e6, // 10MB
 			// https://github.com/segmentio/kafka-go/issues/800#issuecomment-981855523
 			WatchPartitionChanges:  true,
 			PartitionWatchInterval: time.Second * 5,
-		}),
+		})
+		r.source = r.reader
 	}
+
+	c.readers[r] = struct{}{}
+
+	return r, nil
 }
 
 // With 自定义reader配置
@@ -50,6 +86,7 @@ func (r *Reader) With(fn func(reader *kafka.Reader)) *Reader {
 }
 
 // Listen 监听消息回调
+// 注意: 消息在回调前已自动提交，回调失败会丢失消息，需要处理成功后提交时使用 Consume
 func (r *Reader) Listen(ctx context.Context, cb MessageListener) error {
 	// r.SetOffset(42) // 设置Offset
 
@@ -67,7 +104,16 @@ func (r *Reader) Reader() *kafka.Reader {
 	return r.reader
 }
 
-// Close 关闭reader
+// Close 关闭reader，已关闭或已随客户端关闭时直接返回
 func (r *Reader) Close() error {
-	return r.reader.Close()
+	r.clara.removeReader(r)
+	return r.close()
+}
+
+// close 关闭底层 Reader，仅关闭一次
+func (r *Reader) close() error {
+	r.closeOnce.Do(func() {
+		r.closeErr = r.source.Close()
+	})
+	return r.closeErr
 }
@@ -0,0 +1,186 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// DecodeError 消息解码失败
+// 解码失败的消息重试也无法成功，Consume 不重试，直接发送到死信 Topic
+type DecodeError struct {
+	ContentType string
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("消息解码失败 [%s]: %v", e.ContentType, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Header 返回消息中第一个指定名称的 header，不存在时返回空字符串
+func Header(message kafka.Message, key string) string {
+	for _, h := range message.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// SchemaVersion 返回消息的 schema 版本
+func SchemaVersion(message kafka.Message) string {
+	return Header(message, HeaderSchemaVersion)
+}
+
+// Decode 使用 codec 解码消息
+// 若消息携带的 content-type 与 codec 不一致，返回 ErrContentTypeMismatch
+func Decode[T any](codec Codec, message kafka.Message) (v T, err error) {
+	contentType := Header(message, HeaderContentType)
+	if contentType != "" && contentType != codec.ContentType() {
+		err = &DecodeError{
+			ContentType: contentType,
+			Err:         fmt.Errorf("%w: 期望 %s", ErrContentTypeMismatch, codec.ContentType()),
+		}
+		return
+	}
+
+	// 指针类型需要先分配内存，例如 *pb.Order
+	var target any = &v
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem()).Interface().(T)
+		target = v
+	}
+
+	err = codec.Unmarshal(message.Value, target)
+	if err != nil {
+		err = &DecodeError{ContentType: codec.ContentType(), Err: err}
+	}
+	return
+}
+
+// KeyFunc 返回消息的 Kafka Key，相同 Key 的消息写入同一分区
+type KeyFunc[T any] func(v T) string
+
+// TypedOptions 泛型读写配置
+type TypedOptions struct {
+	schemaVersion string
+}
+
+// TypedOption 泛型读写选项
+type TypedOption func(*TypedOptions)
+
+// WithSchemaVersion 设置消息的 schema 版本，发送时写入 HeaderSchemaVersion
+func WithSchemaVersion(version string) TypedOption {
+	return func(o *TypedOptions) {
+		o.schemaVersion = version
+	}
+}
+
+// TypedWriter 泛型消息写入
+//
+// 使用示例:
+//
+//	w := clara.NewTypedWriter(clara.NewWriter(brokers, "orders"), clara.JSONCodec{}, func(o Order) string {
+//	    return o.ID
+//	}, clara.WithSchemaVersion("2"))
+//	err := w.Send(ctx, order)
+type TypedWriter[T any] struct {
+	writer  *Writer
+	codec   Codec
+	key     KeyFunc[T]
+	options *TypedOptions
+}
+
+// NewTypedWriter 创建泛型消息写入，key 为 nil 时消息不设置 Key
+func NewTypedWriter[T any](w *Writer, codec Codec, key KeyFunc[T], opts ...TypedOption) *TypedWriter[T] {
+	options := &TypedOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &TypedWriter[T]{
+		writer:  w,
+		codec:   codec,
+		key:     key,
+		options: options,
+	}
+}
+
+// Send 编码并发送消息，同时写入 content-type 和 schema 版本 header
+func (w *TypedWriter[T]) Send(ctx context.Context, values ...T) error {
+	messages := make([]kafka.Message, len(values))
+	for i, v := range values {
+		b, err := w.codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		headers := []kafka.Header{{Key: HeaderContentType, Value: []byte(w.codec.ContentType())}}
+		if w.options.schemaVersion != "" {
+			headers = append(headers, kafka.Header{Key: HeaderSchemaVersion, Value: []byte(w.options.schemaVersion)})
+		}
+
+		messages[i] = kafka.Message{Value: b, Headers: headers}
+		if w.key != nil {
+			messages[i].Key = []byte(w.key(v))
+		}
+	}
+
+	return w.writer.SendMessages(ctx, messages...)
+}
+
+// Writer 返回底层 Writer
+func (w *TypedWriter[T]) Writer() *Writer {
+	return w.writer
+}
+
+// TypedHandler 泛型消息处理函数
+type TypedHandler[T any] func(ctx context.Context, message kafka.Message, v T) error
+
+// TypedReader 泛型消息读取
+//
+// 使用示例:
+//
+//	r := clara.NewTypedReader[Order](clara.NewReader(brokers, "orders", "order-service"), clara.JSONCodec{})
+//	err := r.Consume(ctx, func(ctx context.Context, message kafka.Message, order Order) error {
+//	    return nil
+//	}, clara.WithDeadLetter("orders-dlq"))
+type TypedReader[T any] struct {
+	reader *Reader
+	codec  Codec
+}
+
+// NewTypedReader 创建泛型消息读取
+func NewTypedReader[T any](r *Reader, codec Codec) *TypedReader[T] {
+	return &TypedReader[T]{
+		reader: r,
+		codec:  codec,
+	}
+}
+
+// Consume 解码并消费消息，消息处理成功后提交 offset
+// 解码失败的消息不会进入 handler 也不会重试，设置 WithDeadLetter 时直接发送到死信 Topic
+func (r *TypedReader[T]) Consume(ctx context.Context, handler TypedHandler[T], opts ...ConsumeOption) error {
+	return r.reader.Consume(ctx, func(ctx context.Context, message kafka.Message) error {
+		v, err := Decode[T](r.codec, message)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, message, v)
+	}, opts...)
+}
+
+// Reader 返回底层 Reader
+func (r *TypedReader[T]) Reader() *Reader {
+	return r.reader
+}
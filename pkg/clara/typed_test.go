@@ -0,0 +1,109 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type typedOrder struct {
+	ID     string  `json:"id"`
+	Amount float64 `json:"amount"`
+}
+
+func TestTypedWriter(t *testing.T) {
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("typed-writer"))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	stub := &stubWriter{}
+	w, err := c.Writer("orders", WithSync(), withMessageWriter(stub))
+	require.NoError(t, err)
+
+	typed := NewTypedWriter(w, JSONCodec{}, func(o typedOrder) string {
+		return o.ID
+	}, WithSchemaVersion("2"))
+	require.NoError(t, typed.Send(context.Background(), typedOrder{ID: "1", Amount: 9.9}, typedOrder{ID: "2"}))
+	sent := stub.written()
+
+	require.Len(t, sent, 2)
+	require.Equal(t, "1", string(sent[0].Key))
+	require.JSONEq(t, `{"id":"1","amount":9.9}`, string(sent[0].Value))
+	require.Equal(t, ContentTypeJSON, Header(sent[0], HeaderContentType))
+	require.Equal(t, "2", SchemaVersion(sent[1]))
+
+	order, err := Decode[typedOrder](JSONCodec{}, sent[0])
+	require.NoError(t, err)
+	require.Equal(t, typedOrder{ID: "1", Amount: 9.9}, order)
+
+	// 未设置 key 时不写入 Key
+	require.NoError(t, NewTypedWriter[*wrapperspb.StringValue](w, ProtoCodec{}, nil).Send(context.Background(), wrapperspb.String("hello")))
+	sent = stub.written()[2:]
+	require.Nil(t, sent[0].Key)
+	require.Empty(t, SchemaVersion(sent[0]))
+
+	value, err := Decode[*wrapperspb.StringValue](ProtoCodec{}, sent[0])
+	require.NoError(t, err)
+	require.Equal(t, "hello", value.GetValue())
+
+	// content-type 不匹配
+	_, err = Decode[typedOrder](JSONCodec{}, sent[0])
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	require.ErrorIs(t, err, ErrContentTypeMismatch)
+	require.Equal(t, ContentTypeProtobuf, decodeErr.ContentType)
+
+	_, err = ProtoCodec{}.Marshal(typedOrder{})
+	require.ErrorIs(t, err, ErrNotProtoMessage)
+}
+
+func TestTypedReader(t *testing.T) {
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("typed-reader"))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	dead := &stubWriter{}
+
+	header := []kafka.Header{{Key: HeaderContentType, Value: []byte(ContentTypeJSON)}}
+	source := newStubSource(
+		kafka.Message{Partition: 0, Offset: 1, Value: []byte(`{"id":"1"}`), Headers: header},
+		kafka.Message{Partition: 0, Offset: 2, Value: []byte(`invalid`), Headers: header},
+	)
+	reader, err := c.newReader("orders", "order-service", source)
+	require.NoError(t, err)
+	r := NewTypedReader[typedOrder](reader, JSONCodec{})
+
+	var orders []typedOrder
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Consume(ctx, func(_ context.Context, _ kafka.Message, order typedOrder) error {
+			orders = append(orders, order)
+			return nil
+		}, WithDeadLetter("orders-dlq"), withDeadLetterWriter(dead))
+	}()
+
+	require.Eventually(t, func() bool {
+		return source.offset(0) == 2
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// 解码失败不重试，直接进入死信 Topic
+	require.Equal(t, []typedOrder{{ID: "1"}}, orders)
+	letters := dead.written()
+	require.Len(t, letters, 1)
+	require.Equal(t, "1", Header(letters[0], HeaderAttempts))
+	require.Equal(t, ContentTypeJSON, Header(letters[0], HeaderContentType))
+	require.Equal(t, 1, dead.closed)
+}
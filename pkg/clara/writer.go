@@ -7,6 +7,9 @@ package clara
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -18,31 +21,110 @@ const (
 	DefaultRetryInterval = 250 * time.Millisecond // 默认重试间隔
 )
 
+// Completion 异步发送完成回调，err 为 nil 时消息已被 broker 确认，否则为重试后仍失败的错误
+// 回调在 Writer 的后台 goroutine 中执行，不能阻塞
+type Completion func(messages []kafka.Message, err error)
+
+// DeliveryError 异步发送失败错误
+type DeliveryError struct {
+	Topic    string
+	Messages []kafka.Message
+	Err      error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("kafka 消息发送失败: topic=%s, messages=%d: %v", e.Topic, len(e.Messages), e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// messageWriter 消息写入，默认为 kafka.Writer
+type messageWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
 type Writer struct {
+	topic  string
+	clara  *Clara
 	writer *kafka.Writer
+	output messageWriter // 消息写入，默认为 writer
 
 	retries       int
 	retryInterval time.Duration
 	timeout       time.Duration
+
+	async      bool
+	completion Completion
+	errors     chan<- error
+	metrics    Metrics
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// delivery 异步发送的消息状态，保存在 kafka.Message.WriterData 中
+type delivery struct {
+	attempt int
+	data    any // 调用方设置的 WriterData
 }
 
 var _ = NewWriter
 
-// NewWriter 创建一个新的 Writer
+// NewWriter 创建一个新的 Writer，相同 broker、客户端配置和 topic 复用同一个 Writer
+// 共享的客户端被并发关闭时使用新的客户端创建
+// opts 可包含 WithClientID、WithSASLScramSHA512、WithTLS 等客户端配置选项
+//
+// 使用示例:
+//
+//	tlsConfig, err := clara.LoadTLSConfig(clara.TLSFiles{CAFile: "ca.pem"})
+//	w := clara.NewWriter(brokers, "logs",
+//	    clara.WithClientID("order-service"),
+//	    clara.WithSASLScramSHA512(username, password),
+//	    clara.WithTLS(tlsConfig),
+//	)
 func NewWriter(brokers []string, topic string, opts ...Option) *Writer {
-	c := New(brokers)
+	for {
+		// 已关闭的客户端已从注册表中移除，再次获取时创建新的客户端
+		if w, err := New(brokers, clientOptions(opts)...).Writer(topic, opts...); err == nil {
+			return w
+		}
+	}
+}
+
+// Writer 获取 topic 对应的 Writer，已存在时直接返回，opts 仅在创建时生效，客户端已关闭时返回 ErrClientClosed
+// 默认异步发送，发送结果通过 WithCompletion / WithErrors / WithMetrics 获取，使用 WithSync 切换为同步发送
+func (c *Clara) Writer(topic string, opts ...Option) (*Writer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	w, exists := c.writers.Get(topic)
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+
+	w, exists := c.writers[topic]
 	if exists {
-		return w
+		return w, nil
 	}
 
-	w = &Writer{
+	w = c.newWriter(topic, opts...)
+	c.writers[topic] = w
+
+	return w, nil
+}
+
+// newWriter 创建不由客户端缓存的 Writer，调用方负责关闭
+func (c *Clara) newWriter(topic string, opts ...Option) *Writer {
+	w := &Writer{
+		topic: topic,
+		clara: c,
 		writer: &kafka.Writer{
-			Addr:                   kafka.TCP(c.brokers...),
+			Addr:                   kafka.TCP(c.config.Brokers...),
 			Topic:                  topic,
+			Transport:              c.transport,
 			AllowAutoTopicCreation: true,                // 自动创建topic
-			Async:                  true,                // 异步
 			Balancer:               &kafka.LeastBytes{}, // 选择分区策略，这里使用最小字节策略（保持）
 			BatchSize:              100,                 // 设置批次大小，以消息数量为单位（选填）
 			BatchBytes:             1024 * 1024,         // 设置批次字节大小上限（选填）
@@ -53,13 +135,22 @@ func NewWriter(brokers []string, topic string, opts ...Option) *Writer {
 		retries:       DefaultRetries,
 		retryInterval: DefaultRetryInterval,
 		timeout:       DefaultTimeout,
+		async:         true,
+		metrics:       nopMetrics{},
 	}
 
 	for _, opt := range opts {
 		opt.apply(w)
 	}
 
-	c.writers.Set(topic, w)
+	// 异步模式通过 Completion 获取 broker 的确认结果
+	w.writer.Async = w.async
+	if w.async {
+		w.writer.Completion = w.complete
+	}
+	if w.output == nil {
+		w.output = w.writer
+	}
 
 	return w
 }
@@ -71,28 +162,214 @@ func (w *Writer) With(fn func(reader *kafka.Writer)) *Writer {
 }
 
 // SendMessages 发送消息到Kafka
-func (w *Writer) SendMessages(ctx context.Context, messages ...kafka.Message) (err error) {
-	for i := 0; i < w.retries; i++ {
-		err = w.writeMessagesWithTimeout(ctx, messages...)
-		if errors.Is(err, kafka.LeaderNotAvailable) || errors.Is(err, kafka.UnknownTopicOrPartition) || errors.Is(err, context.DeadlineExceeded) {
-			time.Sleep(w.retryInterval)
-			continue
+// 同步模式下等待 broker 确认，失败时按 WithRetries / WithRetryInterval 重试后返回错误；
+// 异步模式下消息进入发送队列后立即返回 nil，发送失败的消息在后台重试，最终结果通过 Completion 回调、错误通道和指标上报
+func (w *Writer) SendMessages(ctx context.Context, messages ...kafka.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if w.async {
+		w.sendAsync(ctx, messages)
+		return nil
+	}
+	return w.sendSync(ctx, messages)
+}
+
+// sendSync 同步发送，仅重试发送失败的消息
+func (w *Writer) sendSync(ctx context.Context, messages []kafka.Message) (err error) {
+	total := len(messages)
+	pending := messages
+
+	for attempt := 1; ; attempt++ {
+		err = w.writeMessagesWithTimeout(ctx, pending...)
+		if err == nil || attempt >= w.retries || !retriable(err) {
+			break
+		}
+
+		var werr kafka.WriteErrors
+		if errors.As(err, &werr) && len(werr) == len(pending) {
+			failed := make([]kafka.Message, 0, werr.Count())
+			for i, e := range werr {
+				if e != nil {
+					failed = append(failed, pending[i])
+				}
+			}
+			pending = failed
+		}
+		w.metrics.Retry(w.topic, len(pending))
+
+		select {
+		case <-ctx.Done():
+			err = errors.Join(err, ctx.Err())
+			w.observe(total-len(pending), nil)
+			w.observe(len(pending), err)
+			return
+		case <-time.After(w.retryInterval):
 		}
-		return
 	}
+
+	failed := 0
+	if err != nil {
+		failed = len(pending)
+		var werr kafka.WriteErrors
+		if errors.As(err, &werr) {
+			failed = werr.Count()
+		}
+	}
+	w.observe(total-failed, nil)
+	w.observe(failed, err)
 	return
 }
 
+// sendAsync 异步发送，写入发送队列失败时同样按重试策略处理
+func (w *Writer) sendAsync(ctx context.Context, messages []kafka.Message) {
+	// 复制消息以免修改调用方的 WriterData
+	messages = slices.Clone(messages)
+	for i := range messages {
+		messages[i].WriterData = &delivery{attempt: 1, data: messages[i].WriterData}
+	}
+
+	if err := w.writeMessagesWithTimeout(ctx, messages...); err != nil {
+		w.complete(messages, err)
+	}
+}
+
+// complete 处理异步发送结果，可重试的失败消息在重试间隔后重新发送，其余消息上报结果
+// 与 sendSync 一致，err 为 kafka.WriteErrors 时按消息判断，仅重试发送失败的消息
+func (w *Writer) complete(messages []kafka.Message, err error) {
+	var werr kafka.WriteErrors
+	if !errors.As(err, &werr) || len(werr) != len(messages) {
+		werr = nil
+	}
+
+	var (
+		retry, succeeded, failed []kafka.Message
+		failedErrs               kafka.WriteErrors
+	)
+	for i, m := range messages {
+		merr := err
+		if werr != nil {
+			merr = werr[i]
+		}
+
+		// 通过 With 等方式直接写入底层 Writer 的消息没有 delivery，不重试
+		d, ok := m.WriterData.(*delivery)
+		if ok {
+			if merr != nil && d.attempt < w.retries && retriable(merr) {
+				d.attempt++
+				retry = append(retry, m)
+				continue
+			}
+			m.WriterData = d.data
+		}
+
+		if merr == nil {
+			succeeded = append(succeeded, m)
+			continue
+		}
+		failed = append(failed, m)
+		failedErrs = append(failedErrs, merr)
+	}
+
+	if len(retry) > 0 {
+		w.metrics.Retry(w.topic, len(retry))
+		time.AfterFunc(w.retryInterval, func() {
+			w.resend(retry)
+		})
+	}
+
+	if len(succeeded) > 0 {
+		w.report(succeeded, nil)
+	}
+
+	if len(failed) > 0 {
+		// 部分失败时上报与失败消息一一对应的错误
+		if werr != nil {
+			err = failedErrs
+		}
+		w.report(failed, err)
+	}
+}
+
+// resend 重新发送消息，Writer 已关闭时消息按发送失败上报
+func (w *Writer) resend(messages []kafka.Message) {
+	// Completion 中的消息已填充 topic，Writer 指定 topic 时消息不能再指定
+	if w.writer.Topic != "" {
+		for i := range messages {
+			messages[i].Topic = ""
+		}
+	}
+
+	if err := w.writeMessagesWithTimeout(context.Background(), messages...); err != nil {
+		w.complete(messages, err)
+	}
+}
+
+// report 上报异步发送的最终结果
+func (w *Writer) report(messages []kafka.Message, err error) {
+	w.observe(len(messages), err)
+
+	if w.completion != nil {
+		w.completion(messages, err)
+	}
+
+	if err != nil && w.errors != nil {
+		// 错误通道已满时丢弃，避免阻塞发送
+		select {
+		case w.errors <- &DeliveryError{Topic: w.topic, Messages: messages, Err: err}:
+		default:
+		}
+	}
+}
+
+// observe 记录发送结果指标
+func (w *Writer) observe(n int, err error) {
+	if n > 0 {
+		w.metrics.ObserveDelivery(w.topic, n, err)
+	}
+}
+
+// retriable 判断错误是否可重试
+func retriable(err error) bool {
+	var werr kafka.WriteErrors
+	if errors.As(err, &werr) {
+		for _, e := range werr {
+			if e != nil && retriable(e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if errors.Is(err, kafka.LeaderNotAvailable) || errors.Is(err, kafka.UnknownTopicOrPartition) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var kerr kafka.Error
+	return errors.As(err, &kerr) && kerr.Temporary()
+}
+
 // writeMessagesWithTimeout 写入消息，带有超时控制
 func (w *Writer) writeMessagesWithTimeout(ctx context.Context, messages ...kafka.Message) (err error) {
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithTimeout(ctx, w.timeout)
 	defer cancel()
 
-	return w.writer.WriteMessages(ctx, messages...)
+	return w.output.WriteMessages(ctx, messages...)
 }
 
-// Close 关闭writer
+// Close 关闭writer，等待队列中的消息发送完成，关闭后到期的重试按发送失败上报
+// 已关闭或已随客户端关闭时直接返回
 func (w *Writer) Close() error {
-	return w.writer.Close()
+	w.clara.removeWriter(w)
+	return w.close()
+}
+
+// close 关闭底层 Writer，仅关闭一次
+func (w *Writer) close() error {
+	w.closeOnce.Do(func() {
+		w.closeErr = w.output.Close()
+	})
+	return w.closeErr
 }
@@ -0,0 +1,308 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package clara
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+// counterValue 读取 counter 的值
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, counter.Write(m))
+	return m.Counter.GetValue()
+}
+
+// stubWriter 代替 kafka.Writer 记录写入的消息，fail 不为 nil 时返回其结果
+type stubWriter struct {
+	mu       sync.Mutex
+	fail     func(messages []kafka.Message) error
+	messages []kafka.Message
+	closed   int
+}
+
+func (s *stubWriter) WriteMessages(_ context.Context, messages ...kafka.Message) error {
+	s.mu.Lock()
+	fail := s.fail
+	s.mu.Unlock()
+
+	if fail != nil {
+		if err := fail(messages); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, messages...)
+	return nil
+}
+
+func (s *stubWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed++
+	return nil
+}
+
+// setFail 设置写入结果
+func (s *stubWriter) setFail(fail func(messages []kafka.Message) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+// written 返回已写入的消息
+func (s *stubWriter) written() []kafka.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages)
+}
+
+func TestWriterSync(t *testing.T) {
+	metrics, err := NewPrometheusMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("writer-sync"))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	stub := &stubWriter{}
+	w, err := c.Writer("logs", WithSync(), WithRetryInterval(time.Millisecond), WithMetrics(metrics), withMessageWriter(stub))
+	require.NoError(t, err)
+	require.False(t, w.writer.Async)
+
+	// 仅重试发送失败的消息
+	var attempts [][]string
+	stub.setFail(func(messages []kafka.Message) error {
+		var values []string
+		for _, m := range messages {
+			values = append(values, string(m.Value))
+		}
+		attempts = append(attempts, values)
+
+		if len(attempts) == 1 {
+			return kafka.WriteErrors{nil, kafka.LeaderNotAvailable}
+		}
+		return nil
+	})
+
+	require.NoError(t, w.SendMessages(context.Background(), kafka.Message{Value: []byte("a")}, kafka.Message{Value: []byte("b")}))
+	require.Equal(t, [][]string{{"a", "b"}, {"b"}}, attempts)
+	require.Equal(t, float64(2), counterValue(t, metrics.messages.WithLabelValues("logs", "success")))
+	require.Equal(t, float64(1), counterValue(t, metrics.retries.WithLabelValues("logs")))
+
+	// 不可重试的错误直接返回
+	attempts = nil
+	stub.setFail(func([]kafka.Message) error {
+		attempts = append(attempts, nil)
+		return kafka.MessageSizeTooLarge
+	})
+	require.ErrorIs(t, w.SendMessages(context.Background(), kafka.Message{Value: []byte("c")}), kafka.MessageSizeTooLarge)
+	require.Len(t, attempts, 1)
+	require.Equal(t, float64(1), counterValue(t, metrics.messages.WithLabelValues("logs", "error")))
+
+	// 超过重试次数后返回错误
+	attempts = nil
+	stub.setFail(func([]kafka.Message) error {
+		attempts = append(attempts, nil)
+		return kafka.UnknownTopicOrPartition
+	})
+	require.ErrorIs(t, w.SendMessages(context.Background(), kafka.Message{Value: []byte("d")}), kafka.UnknownTopicOrPartition)
+	require.Len(t, attempts, DefaultRetries)
+}
+
+func TestWriterAsync(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		results = make(chan []kafka.Message, 10)
+		errs    = make(chan error, 10)
+		writes  int
+	)
+
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("writer-async"))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	stub := &stubWriter{}
+	w, err := c.Writer("logs",
+		withMessageWriter(stub),
+		WithRetries(2),
+		WithRetryInterval(time.Millisecond),
+		WithErrors(errs),
+		WithCompletion(func(messages []kafka.Message, err error) {
+			if err == nil {
+				results <- messages
+			}
+		}),
+	)
+	require.NoError(t, err)
+	require.True(t, w.writer.Async)
+
+	// 模拟 broker 异步确认: 首次写入失败，重试后成功
+	stub.setFail(func(messages []kafka.Message) error {
+		mu.Lock()
+		writes++
+		n := writes
+		mu.Unlock()
+
+		for _, m := range messages {
+			require.Empty(t, m.Topic)
+		}
+
+		// 与 kafka-go 一致，Completion 中的消息填充 topic
+		completed := make([]kafka.Message, len(messages))
+		for i, m := range messages {
+			m.Topic = "logs"
+			completed[i] = m
+		}
+
+		go func() {
+			if n == 1 || string(completed[0].Value) == "poison" {
+				w.complete(completed, kafka.LeaderNotAvailable)
+				return
+			}
+			w.complete(completed, nil)
+		}()
+		return nil
+	})
+
+	require.NoError(t, w.SendMessages(context.Background(), kafka.Message{Value: []byte("a"), WriterData: "data"}))
+
+	select {
+	case messages := <-results:
+		require.Len(t, messages, 1)
+		require.Equal(t, "a", string(messages[0].Value))
+		require.Equal(t, "data", messages[0].WriterData)
+	case <-time.After(time.Second):
+		t.Fatal("消息未发送成功")
+	}
+
+	// 重试次数用尽后通过错误通道上报
+	require.NoError(t, w.SendMessages(context.Background(), kafka.Message{Value: []byte("poison")}))
+
+	select {
+	case err := <-errs:
+		var derr *DeliveryError
+		require.ErrorAs(t, err, &derr)
+		require.ErrorIs(t, err, kafka.LeaderNotAvailable)
+		require.Equal(t, "logs", derr.Topic)
+		require.Nil(t, derr.Messages[0].WriterData)
+	case <-time.After(time.Second):
+		t.Fatal("未上报发送失败")
+	}
+
+	mu.Lock()
+	require.Equal(t, 4, writes)
+	mu.Unlock()
+
+	// 随客户端关闭后再次关闭不重复关闭底层 Writer
+	require.NoError(t, c.Close())
+	require.NoError(t, w.Close())
+	require.Equal(t, 1, stub.closed)
+}
+
+func TestWriterAsyncPartial(t *testing.T) {
+	c := New([]string{"127.0.0.1:9092"}, WithClientID("writer-async-partial"))
+	defer func() {
+		_ = c.Close()
+	}()
+
+	var (
+		mu     sync.Mutex
+		writes int
+	)
+	results := make(chan []kafka.Message, 10)
+	errs := make(chan error, 10)
+
+	stub := &stubWriter{}
+	w, err := c.Writer("logs",
+		withMessageWriter(stub),
+		WithRetries(2),
+		WithRetryInterval(time.Millisecond),
+		WithErrors(errs),
+		WithCompletion(func(messages []kafka.Message, err error) {
+			if err == nil {
+				results <- messages
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	// 首次写入 b 失败、c 不可重试，重试时仅重新发送 b
+	stub.setFail(func(messages []kafka.Message) error {
+		mu.Lock()
+		writes++
+		n := writes
+		mu.Unlock()
+
+		completed := slices.Clone(messages)
+		go func() {
+			if n == 1 {
+				w.complete(completed, kafka.WriteErrors{nil, kafka.LeaderNotAvailable, kafka.MessageSizeTooLarge})
+				return
+			}
+			w.complete(completed, nil)
+		}()
+		return nil
+	})
+
+	require.NoError(t, w.SendMessages(context.Background(),
+		kafka.Message{Value: []byte("a")},
+		kafka.Message{Value: []byte("b")},
+		kafka.Message{Value: []byte("c")},
+	))
+
+	var delivered []string
+	for range 2 {
+		select {
+		case messages := <-results:
+			for _, m := range messages {
+				delivered = append(delivered, string(m.Value))
+			}
+		case <-time.After(time.Second):
+			t.Fatal("消息未发送成功")
+		}
+	}
+	require.Equal(t, []string{"a", "b"}, delivered)
+
+	select {
+	case err = <-errs:
+		var derr *DeliveryError
+		require.ErrorAs(t, err, &derr)
+		require.Len(t, derr.Messages, 1)
+		require.Equal(t, "c", string(derr.Messages[0].Value))
+		require.Equal(t, kafka.WriteErrors{kafka.MessageSizeTooLarge}, derr.Err)
+	case <-time.After(time.Second):
+		t.Fatal("未上报发送失败")
+	}
+
+	var written []string
+	for _, m := range stub.written() {
+		written = append(written, string(m.Value))
+	}
+	require.Equal(t, []string{"a", "b", "c", "b"}, written)
+}
+
+func TestRetriable(t *testing.T) {
+	require.True(t, retriable(kafka.LeaderNotAvailable))
+	require.True(t, retriable(kafka.NotEnoughReplicas))
+	require.True(t, retriable(context.DeadlineExceeded))
+	require.True(t, retriable(kafka.WriteErrors{nil, kafka.RequestTimedOut}))
+	require.False(t, retriable(kafka.WriteErrors{nil, kafka.MessageSizeTooLarge}))
+	require.False(t, retriable(errors.New("boom")))
+}
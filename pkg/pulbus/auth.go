@@ -0,0 +1,153 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/apache/pulsar-client-go/pulsaradmin"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/admin/auth"
+	"go.uber.org/zap"
+)
+
+// OAuth2Config OAuth2 client credentials 认证配置
+type OAuth2Config struct {
+	IssuerURL string // 授权服务地址
+	ClientID  string // 客户端 ID，仅 Admin 使用，client 从 KeyFile 读取
+	Audience  string // 授权范围
+	KeyFile   string // 包含 client_id 和 client_secret 的凭证文件路径
+	Scope     string // 授权 scope，可选
+}
+
+// TLSConfig TLS 配置，同时设置 CertFile 和 KeyFile 时使用 mTLS 认证
+type TLSConfig struct {
+	TrustCertsFile   string // CA 证书文件路径
+	CertFile         string // 客户端证书文件路径
+	KeyFile          string // 客户端私钥文件路径
+	AllowInsecure    bool   // 是否信任未经验证的服务端证书
+	ValidateHostname bool   // 是否验证服务端主机名
+}
+
+// mutual 是否配置了客户端证书
+func (cfg TLSConfig) mutual() bool {
+	return cfg.CertFile != "" && cfg.KeyFile != ""
+}
+
+// WithToken 使用静态 token 认证
+func WithToken(token string) Option {
+	return func(bus *Pulbus) {
+		bus.clientOptions.Authentication = pulsar.NewAuthenticationToken(token)
+	}
+}
+
+// WithTokenFile 使用文件中的 token 认证，每次认证时重新读取文件，token 轮换后无需重启
+func WithTokenFile(path string) Option {
+	return func(bus *Pulbus) {
+		bus.clientOptions.Authentication = pulsar.NewAuthenticationTokenFromFile(path)
+	}
+}
+
+// WithOAuth2 使用 OAuth2 client credentials 认证
+//
+// 使用示例:
+//
+//	bus, err := New(url, WithOAuth2(OAuth2Config{
+//	    IssuerURL: "https://auth.example.com",
+//	    Audience:  "urn:sn:pulsar:nexa:production",
+//	    KeyFile:   "/etc/pulsar/credentials.json",
+//	}))
+func WithOAuth2(cfg OAuth2Config) Option {
+	return func(bus *Pulbus) {
+		params := map[string]string{
+			"type":       "client_credentials",
+			"issuerUrl":  cfg.IssuerURL,
+			"audience":   cfg.Audience,
+			"privateKey": cfg.KeyFile,
+		}
+		if cfg.Scope != "" {
+			params["scope"] = cfg.Scope
+		}
+		bus.clientOptions.Authentication = pulsar.NewAuthenticationOAuth2(params)
+	}
+}
+
+// WithTLS 设置 TLS 连接，同时设置 CertFile 和 KeyFile 时使用客户端证书认证，并覆盖其他认证方式
+// 服务地址需要使用 pulsar+ssl:// 协议
+func WithTLS(cfg TLSConfig) Option {
+	return func(bus *Pulbus) {
+		bus.clientOptions.TLSTrustCertsFilePath = cfg.TrustCertsFile
+		bus.clientOptions.TLSAllowInsecureConnection = cfg.AllowInsecure
+		bus.clientOptions.TLSValidateHostname = cfg.ValidateHostname
+
+		if cfg.mutual() {
+			bus.clientOptions.TLSCertificateFile = cfg.CertFile
+			bus.clientOptions.TLSKeyFilePath = cfg.KeyFile
+			bus.clientOptions.Authentication = pulsar.NewAuthenticationTLS(cfg.CertFile, cfg.KeyFile)
+		}
+	}
+}
+
+// WithConnectionTimeout 设置建立 TCP 连接的超时时间，默认 10s
+func WithConnectionTimeout(timeout time.Duration) Option {
+	return func(bus *Pulbus) {
+		bus.clientOptions.ConnectionTimeout = timeout
+	}
+}
+
+// WithOperationTimeout 设置 producer / consumer 创建等操作的超时时间，默认 30s
+func WithOperationTimeout(timeout time.Duration) Option {
+	return func(bus *Pulbus) {
+		bus.clientOptions.OperationTimeout = timeout
+	}
+}
+
+// WithLogger 将 pulsar client 的日志输出到 zap，logger 为空时使用 zap.L()
+func WithLogger(logger *zap.Logger) Option {
+	return func(bus *Pulbus) {
+		bus.clientOptions.Logger = NewZapLogger(logger)
+	}
+}
+
+// WithAdminToken Admin 使用静态 token 认证
+func WithAdminToken(token string) AdminOption {
+	return func(cfg *pulsaradmin.Config) {
+		cfg.Token = token
+	}
+}
+
+// WithAdminTokenFile Admin 使用文件中的 token 认证
+func WithAdminTokenFile(path string) AdminOption {
+	return func(cfg *pulsaradmin.Config) {
+		cfg.TokenFile = path
+	}
+}
+
+// WithAdminOAuth2 Admin 使用 OAuth2 client credentials 认证
+func WithAdminOAuth2(oauth OAuth2Config) AdminOption {
+	return func(cfg *pulsaradmin.Config) {
+		cfg.AuthPlugin = auth.OAuth2PluginShortName
+		cfg.IssuerEndpoint = oauth.IssuerURL
+		cfg.ClientID = oauth.ClientID
+		cfg.Audience = oauth.Audience
+		cfg.KeyFile = oauth.KeyFile
+		cfg.Scope = oauth.Scope
+	}
+}
+
+// WithAdminTLS Admin 使用 TLS 连接，同时设置 CertFile 和 KeyFile 时使用客户端证书认证
+// 注意: Admin 的 token 认证优先级低于客户端证书认证
+func WithAdminTLS(tls TLSConfig) AdminOption {
+	return func(cfg *pulsaradmin.Config) {
+		cfg.TLSTrustCertsFilePath = tls.TrustCertsFile
+		cfg.TLSAllowInsecureConnection = tls.AllowInsecure
+		cfg.TLSEnableHostnameVerification = tls.ValidateHostname
+
+		if tls.mutual() {
+			cfg.TLSCertFile = tls.CertFile
+			cfg.TLSKeyFile = tls.KeyFile
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"github.com/apache/pulsar-client-go/pulsaradmin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestClientOptions(t *testing.T) {
+	bus := &Pulbus{}
+	for _, opt := range []Option{
+		WithToken("token"),
+		WithTLS(TLSConfig{TrustCertsFile: "ca.pem", ValidateHostname: true}),
+		WithConnectionTimeout(5 * time.Second),
+		WithOperationTimeout(10 * time.Second),
+		WithLogger(zap.NewNop()),
+	} {
+		opt(bus)
+	}
+
+	options := bus.clientOptions
+	require.NotNil(t, options.Authentication)
+	require.Equal(t, "ca.pem", options.TLSTrustCertsFilePath)
+	require.True(t, options.TLSValidateHostname)
+	require.Empty(t, options.TLSCertificateFile)
+	require.Equal(t, 5*time.Second, options.ConnectionTimeout)
+	require.Equal(t, 10*time.Second, options.OperationTimeout)
+	require.IsType(t, &zapLogger{}, options.Logger)
+
+	// 客户端证书认证
+	WithTLS(TLSConfig{CertFile: "client.pem", KeyFile: "client.key"})(bus)
+	require.Equal(t, "client.pem", bus.clientOptions.TLSCertificateFile)
+	require.Equal(t, "client.key", bus.clientOptions.TLSKeyFilePath)
+}
+
+func TestAdminOptions(t *testing.T) {
+	cfg := &pulsaradmin.Config{}
+	WithAdminOAuth2(OAuth2Config{IssuerURL: "https://auth.example.com", ClientID: "nexa", Audience: "pulsar", KeyFile: "credentials.json"})(cfg)
+	WithAdminTLS(TLSConfig{TrustCertsFile: "ca.pem", CertFile: "client.pem", KeyFile: "client.key"})(cfg)
+
+	require.Equal(t, "oauth2", cfg.AuthPlugin)
+	require.Equal(t, "https://auth.example.com", cfg.IssuerEndpoint)
+	require.Equal(t, "credentials.json", cfg.KeyFile)
+	require.Equal(t, "ca.pem", cfg.TLSTrustCertsFilePath)
+	require.Equal(t, "client.pem", cfg.TLSCertFile)
+
+	admin, err := NewAdmin("http://127.0.0.1:8080", WithAdminToken("token"))
+	require.NoError(t, err)
+	require.NotNil(t, admin)
+}
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := NewZapLogger(zap.New(core))
+
+	logger.SubLogger(log.Fields{"topic": "orders"}).WithField("producer", "p1").Infof("connected %d", 1)
+	logger.WithError(errors.New("boom")).Warn("reconnecting")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	require.Equal(t, "pulsar", entries[0].LoggerName)
+	require.Equal(t, "connected 1", entries[0].Message)
+	require.Equal(t, map[string]any{"topic": "orders", "producer": "p1"}, entries[0].ContextMap())
+	require.Equal(t, zapcore.WarnLevel, entries[1].Level)
+	require.Equal(t, "boom", entries[1].ContextMap()["error"])
+}
@@ -0,0 +1,177 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultBatchTimeout = time.Second
+)
+
+// BatchHandler 批量消息处理函数类型
+// 返回 nil 时整批 ack，返回 *BatchError 时仅重投失败的消息，返回其他错误时整批重投
+type BatchHandler func(msgs []pulsar.Message) error
+
+// BatchError 批量处理部分失败
+type BatchError struct {
+	Indexes []int // 处理失败的消息下标
+	Err     error
+}
+
+// NewBatchError 创建批量处理部分失败错误
+//
+// 使用示例:
+//
+//	return NewBatchError(err, 1, 3)
+func NewBatchError(err error, indexes ...int) *BatchError {
+	return &BatchError{
+		Indexes: indexes,
+		Err:     err,
+	}
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("批量处理失败 %v: %v", e.Indexes, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// WithBatchSize 设置批量消费每批最大消息数量，默认 100
+func WithBatchSize(size int) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.batchSize = size
+	}
+}
+
+// WithBatchTimeout 设置批量消费等待凑满一批的最长时间，默认 1 秒
+func WithBatchTimeout(timeout time.Duration) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.batchTimeout = timeout
+	}
+}
+
+// ConsumeBatch 批量消费消息
+// 消息凑满 WithBatchSize 或距本批第一条消息超过 WithBatchTimeout 时调用 handler
+// context 取消后处理已接收的消息再返回
+//
+// 使用示例:
+//
+//	err := bus.ConsumeBatch(ctx, "events", "event-sub", func(msgs []pulsar.Message) error {
+//	    return repo.BulkInsert(ctx, msgs)
+//	}, WithBatchSize(500), WithBatchTimeout(2*time.Second))
+func (bus *Pulbus) ConsumeBatch(ctx context.Context, topic, subscription string, handler BatchHandler, opts ...ConsumerOption) error {
+	options := newConsumerOptions(opts...)
+
+	// 使用缓存的 consumer
+	consumer, err := bus.getConsumer(topic, subscription, options)
+	if err != nil {
+		return err
+	}
+
+	var end func()
+	ctx, end, err = bus.begin(ctx, consumer)
+	if err != nil {
+		return err
+	}
+	defer end()
+
+	return consumer.consumeBatch(ctx, consumer.Chan(), handler, options)
+}
+
+// consumeBatch 从 channel 收集消息并批量处理
+func (consumer *Consumer) consumeBatch(ctx context.Context, messages <-chan pulsar.ConsumerMessage, handler BatchHandler, options *ConsumerOptions) error {
+	batch := make([]pulsar.Message, 0, options.batchSize)
+
+	var timeout <-chan time.Time
+	flush := func() {
+		if len(batch) > 0 {
+			consumer.handleBatch(batch, handler, options)
+		}
+		batch = make([]pulsar.Message, 0, options.batchSize)
+		timeout = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return context.Cause(ctx)
+		case <-timeout:
+			flush()
+		case cm := <-messages:
+			batch = append(batch, cm.Message)
+			if len(batch) == 1 {
+				timeout = time.After(options.batchTimeout)
+			}
+			if len(batch) >= options.batchSize {
+				flush()
+			}
+		}
+	}
+}
+
+// handleBatch 批量处理消息，成功的消息一次性 ack，失败的消息重投
+func (consumer *Consumer) handleBatch(msgs []pulsar.Message, handler BatchHandler, options *ConsumerOptions) {
+	deliveries := make([]*delivery, len(msgs))
+	for i, msg := range msgs {
+		d, done := consumer.bus.track(consumer, msg)
+		deliveries[i] = d
+		defer done()
+	}
+
+	start := time.Now()
+	err := handler(msgs)
+	consumer.bus.metricsHook().ObserveHandle(consumer.key.Topic, consumer.key.Subscription, time.Since(start), err)
+
+	var failed []int
+	var batchErr *BatchError
+	switch {
+	case err == nil:
+	case errors.As(err, &batchErr):
+		failed = batchErr.Indexes
+	default:
+		failed = make([]int, len(msgs))
+		for i := range msgs {
+			failed[i] = i
+		}
+	}
+
+	ids := make([]pulsar.MessageID, 0, len(msgs))
+	for i, msg := range msgs {
+		if slices.Contains(failed, i) {
+			consumer.retry(consumer.bus.extract(context.Background(), msg), msg, options)
+			continue
+		}
+
+		// 已在事务中 ack
+		if deliveries[i].acked.Load() {
+			continue
+		}
+		ids = append(ids, msg.ID())
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	err = consumer.AckIDList(ids)
+	if err != nil {
+		zap.L().Warn("[Pulsar Consumer] 批量 Ack 失败", zap.String("topic", consumer.key.Topic), zap.String("subscription", consumer.key.Subscription), zap.Int("count", len(ids)), zap.Error(err))
+		return
+	}
+	consumer.bus.metricsHook().Ack(consumer.key.Topic, consumer.key.Subscription, len(ids))
+}
@@ -0,0 +1,70 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumeBatch(t *testing.T) {
+	stub := &stubConsumer{}
+	consumer := &Consumer{bus: &Pulbus{}, key: ConsumerKey{Topic: "events", Subscription: "event-sub"}, Consumer: stub}
+
+	var sizes []int
+	handler := func(msgs []pulsar.Message) error {
+		sizes = append(sizes, len(msgs))
+		if len(msgs) == 3 {
+			return NewBatchError(errors.New("duplicate"), 0, 2)
+		}
+		return nil
+	}
+
+	messages := make(chan pulsar.ConsumerMessage)
+	ctx, cancel := context.WithCancel(context.Background())
+	options := newConsumerOptions(WithBatchSize(4), WithBatchTimeout(20*time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- consumer.consumeBatch(ctx, messages, handler, options)
+	}()
+
+	// 凑满一批
+	for i := 0; i < 4; i++ {
+		messages <- pulsar.ConsumerMessage{Message: &stubMessage{}}
+	}
+
+	// 超时触发
+	for i := 0; i < 3; i++ {
+		messages <- pulsar.ConsumerMessage{Message: &stubMessage{}}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// 取消时处理剩余消息
+	messages <- pulsar.ConsumerMessage{Message: &stubMessage{}}
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	require.Equal(t, []int{4, 3, 1}, sizes)
+	require.Equal(t, int64(6), stub.acked.Load())
+	require.Equal(t, int64(2), stub.nacked.Load())
+}
+
+func TestBatchErrorWholeBatch(t *testing.T) {
+	stub := &stubConsumer{}
+	consumer := &Consumer{bus: &Pulbus{}, Consumer: stub}
+
+	consumer.handleBatch([]pulsar.Message{&stubMessage{}, &stubMessage{}}, func([]pulsar.Message) error {
+		return errors.New("database unavailable")
+	}, newConsumerOptions())
+
+	require.Equal(t, int64(0), stub.acked.Load())
+	require.Equal(t, int64(2), stub.nacked.Load())
+}
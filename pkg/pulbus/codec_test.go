@@ -0,0 +1,153 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecPayload struct {
+	ID   int64  `json:"id" msgpack:"id"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+// stubMessage 仅用于测试解码的消息
+type stubMessage struct {
+	pulsar.Message
+
+	key        string
+	payload    []byte
+	properties map[string]string
+}
+
+func (m *stubMessage) ID() pulsar.MessageID {
+	return nil
+}
+
+func (m *stubMessage) Topic() string {
+	return ""
+}
+
+func (m *stubMessage) RedeliveryCount() uint32 {
+	return 0
+}
+
+func (m *stubMessage) Key() string {
+	return m.key
+}
+
+func (m *stubMessage) OrderingKey() string {
+	return ""
+}
+
+func (m *stubMessage) Payload() []byte {
+	return m.payload
+}
+
+func (m *stubMessage) Properties() map[string]string {
+	return m.properties
+}
+
+// recordBus 记录发送的消息，Consume 时依次交给 handler 处理
+type recordBus struct {
+	Bus
+
+	messages []*pulsar.ProducerMessage
+}
+
+func (b *recordBus) Send(_ context.Context, _ string, messageOpts ...ProducerOption) error {
+	msg, err := buildMessage(messageOpts...)
+	if err != nil {
+		return err
+	}
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+func (b *recordBus) Consume(_ context.Context, _, _ string, handler MessageHandler, _ ...ConsumerOption) error {
+	for _, msg := range b.messages {
+		if err := handler(&stubMessage{key: msg.Key, payload: msg.Payload, properties: msg.Properties}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTypedBus(t *testing.T) {
+	bus := &recordBus{}
+	ctx := context.Background()
+
+	producer := NewTypedProducer[codecPayload](bus, "orders", MsgpackCodec{})
+	require.NoError(t, producer.Send(ctx, codecPayload{ID: 1, Name: "nexa"}, WithProducerKey("1")))
+	require.Len(t, bus.messages, 1)
+	require.Equal(t, ContentTypeMsgpack, bus.messages[0].Properties[PropertyContentType])
+
+	var received []codecPayload
+	err := Subscribe(ctx, bus, "orders", "order-sub", MsgpackCodec{}, func(msg pulsar.Message, v codecPayload) error {
+		require.Equal(t, "1", msg.Key())
+		received = append(received, v)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []codecPayload{{ID: 1, Name: "nexa"}}, received)
+}
+
+func TestCodecs(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec{}, MsgpackCodec{}} {
+		t.Run(codec.ContentType(), func(t *testing.T) {
+			b, err := codec.Marshal(codecPayload{ID: 1, Name: "nexa"})
+			require.NoError(t, err)
+
+			msg := &stubMessage{payload: b, properties: map[string]string{PropertyContentType: codec.ContentType()}}
+
+			var v codecPayload
+			v, err = Decode[codecPayload](codec, msg)
+			require.NoError(t, err)
+			require.Equal(t, codecPayload{ID: 1, Name: "nexa"}, v)
+
+			var p *codecPayload
+			p, err = Decode[*codecPayload](codec, msg)
+			require.NoError(t, err)
+			require.Equal(t, "nexa", p.Name)
+		})
+	}
+}
+
+func TestProtoCodec(t *testing.T) {
+	codec := ProtoCodec{}
+
+	b, err := codec.Marshal(wrapperspb.String("nexa"))
+	require.NoError(t, err)
+
+	var v *wrapperspb.StringValue
+	v, err = Decode[*wrapperspb.StringValue](codec, &stubMessage{payload: b})
+	require.NoError(t, err)
+	require.Equal(t, "nexa", v.GetValue())
+
+	_, err = codec.Marshal(codecPayload{})
+	require.ErrorIs(t, err, ErrNotProtoMessage)
+}
+
+func TestDecodeError(t *testing.T) {
+	msg := &stubMessage{
+		payload:    []byte(`{"id":1}`),
+		properties: map[string]string{PropertyContentType: ContentTypeMsgpack},
+	}
+
+	_, err := Decode[codecPayload](JSONCodec{}, msg)
+	require.ErrorIs(t, err, ErrContentTypeMismatch)
+
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+
+	_, err = Decode[codecPayload](JSONCodec{}, &stubMessage{payload: []byte("invalid")})
+	require.ErrorAs(t, err, &decodeErr)
+	require.Equal(t, ContentTypeJSON, decodeErr.ContentType)
+}
@@ -6,9 +6,13 @@ package pulbus
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
-	"github.com/bytedance/sonic"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -17,21 +21,86 @@ const (
 	defaultConsumerChannelSize = 200
 )
 
+var ErrConsumerOptionsConflict = errors.New("consumer 已使用不同的选项创建")
+
 // MessageHandler 消息处理函数类型
 type MessageHandler func(msg pulsar.Message) error
 
+// ContextHandler 带 context 的消息处理函数类型
+// context 携带从消息属性中提取的链路上下文，消费停止时不会被取消，保证处理中的消息可以完成
+type ContextHandler func(ctx context.Context, msg pulsar.Message) error
+
+// withContext 将 MessageHandler 转换为 ContextHandler
+func (handler MessageHandler) withContext() ContextHandler {
+	return func(_ context.Context, msg pulsar.Message) error {
+		return handler(msg)
+	}
+}
+
 // ConsumerKey 生成 consumer 的唯一标识
 type ConsumerKey struct {
 	Topic        string
 	Subscription string
 }
 
+// DecodeErrorHandler 消息解码失败处理函数类型
+type DecodeErrorHandler func(msg pulsar.Message, err error)
+
 type ConsumerOptions struct {
 	channelSize int
+
+	decodeErrorHandler DecodeErrorHandler
+
+	maxRedeliveries uint32                  // 最大投递次数，超过后进入死信 Topic，0 表示不限制
+	nackBackoff     *ExponentialNackBackoff // Nack 重投退避策略
+	retryLetter     bool                    // 是否启用重试 Topic
+
+	subscriptionType  pulsar.SubscriptionType            // 订阅类型，默认 Shared
+	initialPosition   pulsar.SubscriptionInitialPosition // 订阅初始位置，默认 Latest
+	topics            []string                           // 额外订阅的 Topic
+	topicsPattern     bool                               // 是否将 topic 作为正则表达式订阅
+	middlewares       []ConsumerMiddleware               // 订阅的中间件
+	receiverQueueSize int                                // 接收队列大小，0 使用 pulsar 默认值
+	schema            pulsar.Schema                      // 消息 Schema
+
+	concurrency int // 并发处理的 worker 数量，小于等于 1 时串行处理
+	maxInFlight int // 处理中的消息数量上限，默认等于 concurrency
+
+	batchSize    int           // 批量消费每批最大消息数量
+	batchTimeout time.Duration // 批量消费等待凑满一批的最长时间
+}
+
+// consumerSpec 创建 consumer 时使用的选项，用于判定缓存的 consumer 选项是否冲突
+type consumerSpec struct {
+	channelSize       int
+	maxRedeliveries   uint32
+	nackBackoff       ExponentialNackBackoff
+	retryLetter       bool
+	subscriptionType  pulsar.SubscriptionType
+	initialPosition   pulsar.SubscriptionInitialPosition
+	topics            string
+	topicsPattern     bool
+	receiverQueueSize int
+	schema            pulsar.Schema
 }
 
 type ConsumerOption func(*ConsumerOptions)
 
+func newConsumerOptions(opts ...ConsumerOption) *ConsumerOptions {
+	options := &ConsumerOptions{
+		channelSize:      defaultConsumerChannelSize,
+		subscriptionType: pulsar.Shared,
+		batchSize:        defaultBatchSize,
+		batchTimeout:     defaultBatchTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
 // WithConsumerChannelSize 设置消费 channel 缓冲大小
 func WithConsumerChannelSize(size int) ConsumerOption {
 	return func(o *ConsumerOptions) {
@@ -39,34 +108,231 @@ func WithConsumerChannelSize(size int) ConsumerOption {
 	}
 }
 
+// WithDecodeErrorHandler 设置消息解码失败处理函数
+// 解码失败的消息重投也无法成功，因此调用 handler 后直接 Ack，不设置时仅记录日志
+// 启用死信 Topic 时，解码失败的消息会直接投递到死信 Topic
+func WithDecodeErrorHandler(handler DecodeErrorHandler) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.decodeErrorHandler = handler
+	}
+}
+
+// WithConsumerMaxRedeliveries 设置最大投递次数
+// 超过次数的消息自动路由到死信 Topic <topic>-DLQ，死信 Topic 会以当前订阅名创建初始订阅，可通过 ReplayDLQ 重新投递
+func WithConsumerMaxRedeliveries(n uint32) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.maxRedeliveries = n
+	}
+}
+
+// WithConsumerNackBackoff 设置 Nack 指数退避重投
+// 第 n 次重投延迟为 minBackoff * 2^n，最大不超过 maxBackoff
+func WithConsumerNackBackoff(minBackoff, maxBackoff time.Duration) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.nackBackoff = &ExponentialNackBackoff{Min: minBackoff, Max: maxBackoff}
+	}
+}
+
+// WithConsumerRetryLetter 启用重试 Topic <topic>-RETRY
+// 处理失败的消息按退避延迟发送到重试 Topic 而非 Nack，超过最大投递次数后进入死信 Topic
+func WithConsumerRetryLetter() ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.retryLetter = true
+	}
+}
+
+// WithConsumerSubscriptionType 设置订阅类型
+// 顺序敏感的场景可使用 pulsar.Failover 或 pulsar.KeyShared，默认 pulsar.Shared
+func WithConsumerSubscriptionType(subscriptionType pulsar.SubscriptionType) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.subscriptionType = subscriptionType
+	}
+}
+
+// WithConsumerInitialPosition 设置新订阅的初始位置，默认 pulsar.SubscriptionPositionLatest
+func WithConsumerInitialPosition(position pulsar.SubscriptionInitialPosition) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.initialPosition = position
+	}
+}
+
+// WithConsumerTopics 同时订阅多个 Topic
+// 使用示例:
+//
+//	bus.Consume(ctx, "orders", "order-sub", handler, WithConsumerTopics("refunds", "payments"))
+func WithConsumerTopics(topics ...string) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.topics = append(o.topics, topics...)
+	}
+}
+
+// WithConsumerTopicsPattern 将 topic 参数作为正则表达式订阅匹配的所有 Topic
+// 注意: 死信和重试 Topic 按 topic 参数命名，正则订阅时不要启用
+// 使用示例:
+//
+//	bus.Consume(ctx, "persistent://public/default/orders-.*", "order-sub", handler, WithConsumerTopicsPattern())
+func WithConsumerTopicsPattern() ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.topicsPattern = true
+	}
+}
+
+// WithConsumerReceiverQueueSize 设置接收队列大小
+func WithConsumerReceiverQueueSize(size int) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.receiverQueueSize = size
+	}
+}
+
+// WithConsumerConcurrency 设置并发处理的 worker 数量
+// 相同 Key（优先使用 OrderingKey）的消息始终由同一个 worker 按顺序处理，无 Key 的消息轮询分发
+// context 取消后停止分发，并等待处理中的消息完成 ack 后返回
+func WithConsumerConcurrency(n int) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithConsumerMaxInFlight 设置并发处理时处理中的消息数量上限，默认等于 worker 数量
+func WithConsumerMaxInFlight(n int) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.maxInFlight = n
+	}
+}
+
+// equal 判断选项是否相同，Schema 按类型和定义比较
+func (spec consumerSpec) equal(other consumerSpec) bool {
+	a, b := spec.schema, other.schema
+	spec.schema, other.schema = nil, nil
+	return spec == other && schemaEqual(a, b)
+}
+
+// spec 返回创建 consumer 时使用的选项
+func (o *ConsumerOptions) spec() consumerSpec {
+	spec := consumerSpec{
+		channelSize:       o.channelSize,
+		maxRedeliveries:   o.maxRedeliveries,
+		retryLetter:       o.retryLetter,
+		subscriptionType:  o.subscriptionType,
+		initialPosition:   o.initialPosition,
+		topics:            strings.Join(o.topics, ","),
+		topicsPattern:     o.topicsPattern,
+		receiverQueueSize: o.receiverQueueSize,
+		schema:            o.schema,
+	}
+	if o.nackBackoff != nil {
+		spec.nackBackoff = *o.nackBackoff
+	}
+	return spec
+}
+
+// apply 将消费选项应用到 pulsar 消费者配置
+func (o *ConsumerOptions) apply(opts *pulsar.ConsumerOptions, topic, subscription string) {
+	opts.SubscriptionName = subscription
+	opts.Type = o.subscriptionType
+	opts.SubscriptionInitialPosition = o.initialPosition
+	opts.ReceiverQueueSize = o.receiverQueueSize
+	opts.Schema = o.schema
+
+	switch {
+	case o.topicsPattern:
+		opts.TopicsPattern = topic
+	case len(o.topics) > 0:
+		opts.Topics = append([]string{topic}, o.topics...)
+	default:
+		opts.Topic = topic
+	}
+
+	if o.nackBackoff != nil {
+		opts.NackBackoffPolicy = o.nackBackoff
+	}
+
+	if o.maxRedeliveries == 0 && !o.retryLetter {
+		return
+	}
+
+	opts.DLQ = &pulsar.DLQPolicy{
+		MaxDeliveries:           o.maxRedeliveries,
+		DeadLetterTopic:         DeadLetterTopic(topic),
+		InitialSubscriptionName: subscription,
+	}
+
+	if o.retryLetter {
+		if opts.DLQ.MaxDeliveries == 0 {
+			opts.DLQ.MaxDeliveries = pulsar.MaxReconsumeTimes
+		}
+		opts.DLQ.RetryLetterTopic = RetryLetterTopic(topic)
+		opts.RetryEnable = true
+	}
+}
+
+// deadLetterEnabled 是否启用了死信 Topic
+func (o *ConsumerOptions) deadLetterEnabled() bool {
+	return o.maxRedeliveries > 0 || o.retryLetter
+}
+
 type Consumer struct {
-	key ConsumerKey
+	key  ConsumerKey
+	bus  *Pulbus
+	spec consumerSpec
+
+	running sync.WaitGroup // 运行中的消费循环
 
 	pulsar.Consumer
 }
 
 // 消费日志记录
-func (consumer *Consumer) log(level zapcore.Level, message string, data pulsar.Message) {
-	b, _ := sonic.Marshal(data)
-	zap.L().Log(level, "[Pulsar Consumer] "+message, zap.ByteString("message", b), zap.String("topic", consumer.key.Topic), zap.String("subscription", consumer.key.Subscription))
+func (consumer *Consumer) log(ctx context.Context, level zapcore.Level, message string, msg pulsar.Message, fields ...zap.Field) {
+	ctx = newConsumerContext(ctx, consumer.key)
+	zap.L().Log(level, "[Pulsar Consumer] "+message, append(messageFields(ctx, msg), fields...)...)
 }
 
 // getConsumer 获取 Consumer
-func (bus *Pulbus) getConsumer(topic, subscription string, opts pulsar.ConsumerOptions) (*Consumer, error) {
+// 相同 ConsumerKey 的 consumer 只会创建一次，若再次获取时使用了不同的选项则返回 ErrConsumerOptionsConflict
+func (bus *Pulbus) getConsumer(topic, subscription string, options *ConsumerOptions) (*Consumer, error) {
+	// 总线停止后不再创建 consumer
+	select {
+	case <-bus.done:
+		return nil, ErrBusClosed
+	default:
+	}
+
+	if options.topicsPattern {
+		topic = bus.resolveTopicsPattern(topic)
+	} else {
+		topic = bus.ResolveTopic(topic)
+	}
+	for i, t := range options.topics {
+		options.topics[i] = bus.ResolveTopic(t)
+	}
+
 	key := ConsumerKey{Topic: topic, Subscription: subscription}
+	spec := options.spec()
+
+	// 同一 ConsumerKey 只创建一个 consumer
+	unlock := bus.consumerLocks.lock(key)
+	defer unlock()
 
 	// 尝试从缓存中获取
 	if c, ok := bus.consumers.Load(key); ok {
-		return c.(*Consumer), nil
+		consumer := c.(*Consumer)
+		if !consumer.spec.equal(spec) {
+			return nil, fmt.Errorf("%w: topic=%s, subscription=%s", ErrConsumerOptionsConflict, topic, subscription)
+		}
+		return consumer, nil
 	}
 
 	consumer := &Consumer{
-		key: key,
+		key:  key,
+		bus:  bus,
+		spec: spec,
 	}
 
 	// 不存在则创建新的 consumer
-	opts.Topic = topic
-	opts.SubscriptionName = subscription
+	opts := pulsar.ConsumerOptions{
+		MessageChannel: make(chan pulsar.ConsumerMessage, options.channelSize),
+	}
+	options.apply(&opts, topic, subscription)
 
 	var err error
 	consumer.Consumer, err = bus.client.Subscribe(opts)
@@ -80,74 +346,160 @@ func (bus *Pulbus) getConsumer(topic, subscription string, opts pulsar.ConsumerO
 }
 
 // 处理消息
-func (consumer *Consumer) handleMessage(msg pulsar.Message, handler MessageHandler) {
+func (consumer *Consumer) handleMessage(ctx context.Context, msg pulsar.Message, handler ContextHandler, options *ConsumerOptions) {
+	d, done := consumer.bus.track(consumer, msg)
+	defer done()
+
+	// 提取链路上下文，消费停止时不取消处理中的消息
+	ctx = consumer.bus.extract(context.WithoutCancel(ctx), msg)
+	ctx = newConsumerContext(ctx, consumer.key)
+
 	// 如果返回失败则 nack 该条消息并继续接收下一条消息
-	err := handler(msg)
+	start := time.Now()
+	err := handler(ctx, msg)
+	consumer.bus.metricsHook().ObserveHandle(consumer.key.Topic, consumer.key.Subscription, time.Since(start), err)
+
+	// 解码失败，交由解码失败处理函数并 ack 消息，避免无限重投
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		consumer.log(ctx, zapcore.ErrorLevel, "消息解码失败", msg, zap.Error(decodeErr))
+		if options.decodeErrorHandler != nil {
+			options.decodeErrorHandler(msg, decodeErr)
+		}
+
+		// 启用死信时直接投递到死信 Topic
+		if options.deadLetterEnabled() {
+			err = consumer.sendToDeadLetter(ctx, msg, decodeErr)
+			if err != nil {
+				consumer.log(ctx, zapcore.ErrorLevel, "投递死信失败，Nack 消息", msg, zap.Error(err))
+				consumer.Nack(msg)
+				consumer.bus.metricsHook().Nack(consumer.key.Topic, consumer.key.Subscription, 1)
+				return
+			}
+			consumer.bus.metricsHook().DeadLetter(consumer.key.Topic, consumer.key.Subscription, 1)
+		}
+		err = nil
+	}
+
 	if err != nil {
-		// 处理失败，nack 消息
-		consumer.Nack(msg)
-		consumer.log(zapcore.WarnLevel, "消息处理失败，Nack 消息", msg)
+		consumer.retry(ctx, msg, options)
+		return
+	}
+
+	// 已在事务中 ack
+	if d.acked.Load() {
 		return
 	}
 
 	// 处理成功，ack 消息
 	err = consumer.Ack(msg)
 	if err != nil {
-		consumer.log(zapcore.WarnLevel, "Ack 失败", msg)
+		consumer.log(ctx, zapcore.WarnLevel, "Ack 失败", msg, zap.Error(err))
+		return
 	}
+	consumer.bus.metricsHook().Ack(consumer.key.Topic, consumer.key.Subscription, 1)
 	return
 }
 
+// retry 处理失败，启用重试 Topic 时按退避延迟重新消费，否则 nack 消息
+func (consumer *Consumer) retry(ctx context.Context, msg pulsar.Message, options *ConsumerOptions) {
+	metrics := consumer.bus.metricsHook()
+	metrics.Nack(consumer.key.Topic, consumer.key.Subscription, 1)
+	if options.willDeadLetter(msg) {
+		metrics.DeadLetter(consumer.key.Topic, consumer.key.Subscription, 1)
+	}
+
+	if options.retryLetter {
+		consumer.ReconsumeLater(msg, options.retryDelay(msg))
+		consumer.log(ctx, zapcore.WarnLevel, "消息处理失败，发送至重试 Topic", msg)
+		return
+	}
+
+	consumer.Nack(msg)
+	consumer.log(ctx, zapcore.WarnLevel, "消息处理失败，Nack 消息", msg)
+}
+
 // ConsumeWithLoop 阻塞消费消息
-func (bus *Pulbus) ConsumeWithLoop(ctx context.Context, topic, subscription string, handler MessageHandler) error {
+func (bus *Pulbus) ConsumeWithLoop(ctx context.Context, topic, subscription string, handler MessageHandler, opts ...ConsumerOption) error {
+	return bus.ConsumeWithLoopContext(ctx, topic, subscription, handler.withContext(), opts...)
+}
+
+// ConsumeWithLoopContext 阻塞消费消息，handler 的 context 携带消息中的链路上下文
+func (bus *Pulbus) ConsumeWithLoopContext(ctx context.Context, topic, subscription string, handler ContextHandler, opts ...ConsumerOption) error {
+	options := newConsumerOptions(opts...)
+	handler = bus.chain(handler, options)
+
 	// 使用缓存的 consumer
-	consumer, err := bus.getConsumer(topic, subscription, pulsar.ConsumerOptions{
-		Type: pulsar.Shared,
-	})
+	consumer, err := bus.getConsumer(topic, subscription, options)
 	if err != nil {
 		return err
 	}
 
+	var end func()
+	ctx, end, err = bus.begin(ctx, consumer)
+	if err != nil {
+		return err
+	}
+	defer end()
+
 	// 持续接收消息
 	var msg pulsar.Message
 	for {
 		msg, err = consumer.Receive(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
 			return err
 		}
 
-		consumer.handleMessage(msg, handler)
+		consumer.handleMessage(ctx, msg, handler, options)
 	}
 }
 
 // Consume 使用 channel 阻塞消费消息
 func (bus *Pulbus) Consume(ctx context.Context, topic, subscription string, handler MessageHandler, opts ...ConsumerOption) error {
-	options := &ConsumerOptions{
-		channelSize: defaultConsumerChannelSize,
-	}
-
-	for _, opt := range opts {
-		opt(options)
-	}
+	return bus.ConsumeContext(ctx, topic, subscription, handler.withContext(), opts...)
+}
 
-	messageChan := make(chan pulsar.ConsumerMessage, options.channelSize)
+// ConsumeContext 使用 channel 阻塞消费消息，handler 的 context 携带消息中的链路上下文
+//
+// 使用示例:
+//
+//	err := bus.ConsumeContext(ctx, "orders", "order-sub", func(ctx context.Context, msg pulsar.Message) error {
+//	    return svc.HandleOrder(ctx, msg.Payload())
+//	})
+func (bus *Pulbus) ConsumeContext(ctx context.Context, topic, subscription string, handler ContextHandler, opts ...ConsumerOption) error {
+	options := newConsumerOptions(opts...)
+	handler = bus.chain(handler, options)
 
 	// 使用缓存的 consumer
-	consumer, err := bus.getConsumer(topic, subscription, pulsar.ConsumerOptions{
-		Type:           pulsar.Shared,
-		MessageChannel: messageChan,
-	})
+	consumer, err := bus.getConsumer(topic, subscription, options)
+	if err != nil {
+		return err
+	}
+
+	var end func()
+	ctx, end, err = bus.begin(ctx, consumer)
 	if err != nil {
 		return err
 	}
+	defer end()
+
+	messageChan := consumer.Chan()
+
+	// 并发处理
+	if options.concurrency > 1 {
+		return newDispatcher(consumer, handler, options).run(ctx, messageChan)
+	}
 
 	// 从 channel 读取消息
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return context.Cause(ctx)
 		case cm := <-messageChan:
-			consumer.handleMessage(cm.Message, handler)
+			consumer.handleMessage(ctx, cm.Message, handler, options)
 		}
 	}
 }
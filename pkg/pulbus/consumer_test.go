@@ -7,7 +7,9 @@ package pulbus
 import (
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,3 +47,135 @@ func TestConsumerOption(t *testing.T) {
 
 	require.Equal(t, options.channelSize, 100)
 }
+
+func TestConsumerDecodeErrorHandler(t *testing.T) {
+	var called bool
+	options := newConsumerOptions(WithDecodeErrorHandler(func(_ pulsar.Message, _ error) {
+		called = true
+	}))
+
+	require.Equal(t, defaultConsumerChannelSize, options.channelSize)
+
+	options.decodeErrorHandler(nil, nil)
+	require.True(t, called)
+}
+
+func TestConsumerLetterOptions(t *testing.T) {
+	options := newConsumerOptions(
+		WithConsumerMaxRedeliveries(5),
+		WithConsumerNackBackoff(time.Second, time.Minute),
+		WithConsumerRetryLetter(),
+	)
+
+	opts := pulsar.ConsumerOptions{}
+	options.apply(&opts, "orders", "order-sub")
+
+	require.True(t, opts.RetryEnable)
+	require.NotNil(t, opts.NackBackoffPolicy)
+	require.NotNil(t, opts.DLQ)
+	require.Equal(t, uint32(5), opts.DLQ.MaxDeliveries)
+	require.Equal(t, "persistent://public/default/orders-DLQ", opts.DLQ.DeadLetterTopic)
+	require.Equal(t, "persistent://public/default/orders-RETRY", opts.DLQ.RetryLetterTopic)
+	require.Equal(t, "order-sub", opts.DLQ.InitialSubscriptionName)
+
+	opts = pulsar.ConsumerOptions{}
+	newConsumerOptions().apply(&opts, "orders", "order-sub")
+	require.Nil(t, opts.DLQ)
+	require.False(t, opts.RetryEnable)
+}
+
+func TestExponentialNackBackoff(t *testing.T) {
+	backoff := &ExponentialNackBackoff{Min: time.Second, Max: time.Minute}
+
+	require.Equal(t, time.Second, backoff.Next(0))
+	require.Equal(t, 4*time.Second, backoff.Next(2))
+	require.Equal(t, time.Minute, backoff.Next(10))
+	require.Equal(t, time.Minute, backoff.Next(100))
+
+	// Min 为 0 时使用下限，不会立即重投
+	backoff = &ExponentialNackBackoff{}
+	require.Equal(t, minNackBackoff, backoff.Next(0))
+	require.Equal(t, minNackBackoff, backoff.Next(2))
+
+	backoff = &ExponentialNackBackoff{Max: time.Second}
+	require.Equal(t, minNackBackoff, backoff.Next(0))
+	require.Equal(t, 8*minNackBackoff, backoff.Next(3))
+	require.Equal(t, time.Second, backoff.Next(100))
+}
+
+func TestConsumerSubscriptionOptions(t *testing.T) {
+	opts := pulsar.ConsumerOptions{}
+	newConsumerOptions(
+		WithConsumerSubscriptionType(pulsar.KeyShared),
+		WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest),
+		WithConsumerReceiverQueueSize(500),
+		WithConsumerTopics("refunds", "payments"),
+	).apply(&opts, "orders", "order-sub")
+
+	require.Equal(t, pulsar.KeyShared, opts.Type)
+	require.Equal(t, pulsar.SubscriptionPositionEarliest, opts.SubscriptionInitialPosition)
+	require.Equal(t, 500, opts.ReceiverQueueSize)
+	require.Equal(t, []string{"orders", "refunds", "payments"}, opts.Topics)
+	require.Empty(t, opts.Topic)
+
+	opts = pulsar.ConsumerOptions{}
+	newConsumerOptions(WithConsumerTopicsPattern()).apply(&opts, "orders-.*", "order-sub")
+	require.Equal(t, pulsar.Shared, opts.Type)
+	require.Equal(t, "orders-.*", opts.TopicsPattern)
+	require.Empty(t, opts.Topic)
+}
+
+func TestConsumerOptionsConflict(t *testing.T) {
+	bus := &Pulbus{}
+
+	key := ConsumerKey{Topic: "orders", Subscription: "order-sub"}
+	cached := &Consumer{key: key, bus: bus, spec: newConsumerOptions().spec()}
+	bus.consumers.Store(key, cached)
+
+	consumer, err := bus.getConsumer("orders", "order-sub", newConsumerOptions(WithDecodeErrorHandler(func(pulsar.Message, error) {})))
+	require.NoError(t, err)
+	require.Same(t, cached, consumer)
+
+	_, err = bus.getConsumer("orders", "order-sub", newConsumerOptions(WithConsumerSubscriptionType(pulsar.Failover)))
+	require.ErrorIs(t, err, ErrConsumerOptionsConflict)
+}
+
+func TestGetConsumerConcurrent(t *testing.T) {
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	client := &countingClient{Client: bus.client}
+	bus.client = client
+
+	definition := `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`
+
+	var wg sync.WaitGroup
+	consumers := make([]*Consumer, 10)
+	for i := range consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 每次调用使用新的 Schema 实例，相同定义不视为冲突
+			c, err := bus.getConsumer("orders", "order-sub", newConsumerOptions(
+				WithConsumerSubscriptionType(pulsar.Exclusive),
+				WithConsumerSchema(pulsar.NewJSONSchema(definition, nil)),
+			))
+			require.NoError(t, err)
+			consumers[i] = c
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), client.subscribes.Load())
+	for _, c := range consumers {
+		require.Same(t, consumers[0], c)
+	}
+
+	_, err := bus.getConsumer("orders", "order-sub", newConsumerOptions(
+		WithConsumerSubscriptionType(pulsar.Exclusive),
+		WithConsumerSchema(sliceSchema{definitions: []string{"other"}}),
+	))
+	require.ErrorIs(t, err, ErrConsumerOptionsConflict)
+}
@@ -0,0 +1,142 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	stdsql "database/sql"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+)
+
+// DefaultDedupeTable 默认去重表名
+const DefaultDedupeTable = "pulbus_dedupe"
+
+// SQLDedupeStore 基于 ent SQL 驱动的去重存储，支持 PostgreSQL、MySQL 和 SQLite，适用于多实例部署
+// 去重表需要预先创建:
+//
+//	CREATE TABLE pulbus_dedupe (
+//	    dedupe_key VARCHAR(255) PRIMARY KEY,
+//	    expires_at TIMESTAMP NOT NULL,
+//	    completed  BOOLEAN NOT NULL DEFAULT FALSE
+//	);
+//	CREATE INDEX pulbus_dedupe_expires_at ON pulbus_dedupe (expires_at);
+//
+// 注意: MySQL 连接不能开启 clientFoundRows，否则重复 key 的影响行数无法区分
+type SQLDedupeStore struct {
+	driver dialect.Driver
+	table  string
+}
+
+var _ DedupeStore = (*SQLDedupeStore)(nil)
+
+// SQLDedupeOption SQL 去重存储选项
+type SQLDedupeOption func(*SQLDedupeStore)
+
+// WithDedupeTable 设置去重表名，默认 pulbus_dedupe
+func WithDedupeTable(table string) SQLDedupeOption {
+	return func(s *SQLDedupeStore) {
+		s.table = table
+	}
+}
+
+// NewSQLDedupeStore 创建 SQL 去重存储，driver 可使用 ent 客户端的驱动或 entsql.OpenDB 创建
+//
+// 使用示例:
+//
+//	store := NewSQLDedupeStore(entsql.OpenDB(dialect.Postgres, db))
+func NewSQLDedupeStore(driver dialect.Driver, opts ...SQLDedupeOption) *SQLDedupeStore {
+	s := &SQLDedupeStore{
+		driver: driver,
+		table:  DefaultDedupeTable,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// exec 执行语句并返回影响行数
+func (s *SQLDedupeStore) exec(ctx context.Context, query string, args []any) (int64, error) {
+	var res stdsql.Result
+	if err := s.driver.Exec(ctx, query, args, &res); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Acquire 占用 key 并在 ttl 后过期，已过期的 key 会被重新占用
+func (s *SQLDedupeStore) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	builder := sql.Dialect(s.driver.Dialect())
+
+	// 删除已过期的同名 key
+	query, args := builder.Delete(s.table).
+		Where(sql.And(sql.EQ("dedupe_key", key), sql.LTE("expires_at", now))).
+		Query()
+	if _, err := s.exec(ctx, query, args); err != nil {
+		return false, err
+	}
+
+	query, args = builder.Insert(s.table).
+		Columns("dedupe_key", "expires_at").
+		Values(key, now.Add(ttl)).
+		OnConflict(sql.ConflictColumns("dedupe_key"), sql.DoNothing()).
+		Query()
+	n, err := s.exec(ctx, query, args)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Release 释放 key
+func (s *SQLDedupeStore) Release(ctx context.Context, key string) error {
+	query, args := sql.Dialect(s.driver.Dialect()).Delete(s.table).
+		Where(sql.EQ("dedupe_key", key)).
+		Query()
+	_, err := s.exec(ctx, query, args)
+	return err
+}
+
+// Complete 将 key 标记为已完成并在 ttl 后过期，key 不存在时直接写入
+func (s *SQLDedupeStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	query, args := sql.Dialect(s.driver.Dialect()).Insert(s.table).
+		Columns("dedupe_key", "expires_at", "completed").
+		Values(key, time.Now().Add(ttl), true).
+		OnConflict(sql.ConflictColumns("dedupe_key"), sql.ResolveWithNewValues()).
+		Query()
+	_, err := s.exec(ctx, query, args)
+	return err
+}
+
+// Completed 判断 key 是否已完成且未过期
+func (s *SQLDedupeStore) Completed(ctx context.Context, key string) (bool, error) {
+	query, args := sql.Dialect(s.driver.Dialect()).
+		Select("dedupe_key").
+		From(sql.Table(s.table)).
+		Where(sql.And(sql.EQ("dedupe_key", key), sql.EQ("completed", true), sql.GT("expires_at", time.Now()))).
+		Query()
+
+	var rows sql.Rows
+	if err := s.driver.Query(ctx, query, args, &rows); err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	return rows.Next(), rows.Err()
+}
+
+// Purge 删除所有过期的 key，返回删除数量，建议定时调用
+func (s *SQLDedupeStore) Purge(ctx context.Context) (int64, error) {
+	query, args := sql.Dialect(s.driver.Dialect()).Delete(s.table).
+		Where(sql.LTE("expires_at", time.Now())).
+		Query()
+	return s.exec(ctx, query, args)
+}
@@ -0,0 +1,114 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go.uber.org/zap"
+)
+
+// dispatcher 并发消费分发器
+// 相同 Key 的消息始终分发到同一个 worker 按顺序处理，无 Key 的消息轮询分发
+type dispatcher struct {
+	consumer *Consumer
+	handler  ContextHandler
+	options  *ConsumerOptions
+
+	workers  []chan pulsar.Message
+	inflight chan struct{} // 处理中的消息数量限制
+	next     int           // 无 Key 消息的下一个 worker
+
+	wg sync.WaitGroup
+}
+
+func newDispatcher(consumer *Consumer, handler ContextHandler, options *ConsumerOptions) *dispatcher {
+	maxInFlight := options.maxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = options.concurrency
+	}
+
+	d := &dispatcher{
+		consumer: consumer,
+		handler:  handler,
+		options:  options,
+		workers:  make([]chan pulsar.Message, options.concurrency),
+		inflight: make(chan struct{}, maxInFlight),
+	}
+
+	for i := range d.workers {
+		d.workers[i] = make(chan pulsar.Message, maxInFlight)
+	}
+
+	return d
+}
+
+// run 启动 worker 并分发消息，ctx 取消后等待处理中的消息完成再返回
+func (d *dispatcher) run(ctx context.Context, messages <-chan pulsar.ConsumerMessage) error {
+	for _, ch := range d.workers {
+		d.wg.Add(1)
+		go d.work(ctx, ch)
+	}
+	defer d.drain()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case cm := <-messages:
+			// 等待处理中的消息数量低于限制
+			select {
+			case d.inflight <- struct{}{}:
+			case <-ctx.Done():
+				// 已接收但未分发的消息 nack 以便尽快重投
+				d.consumer.Nack(cm.Message)
+				return context.Cause(ctx)
+			}
+
+			d.workers[d.index(cm.Message)] <- cm.Message
+		}
+	}
+}
+
+// work 顺序处理分发到该 worker 的消息
+func (d *dispatcher) work(ctx context.Context, ch <-chan pulsar.Message) {
+	defer d.wg.Done()
+
+	for msg := range ch {
+		d.consumer.handleMessage(ctx, msg, d.handler, d.options)
+		<-d.inflight
+	}
+}
+
+// drain 停止分发并等待所有 worker 处理完已分发的消息
+func (d *dispatcher) drain() {
+	for _, ch := range d.workers {
+		close(ch)
+	}
+	d.wg.Wait()
+
+	zap.L().Info("[Pulsar Consumer] 并发消费已停止，处理中的消息已完成", zap.String("topic", d.consumer.key.Topic), zap.String("subscription", d.consumer.key.Subscription))
+}
+
+// index 计算消息分发的 worker
+func (d *dispatcher) index(msg pulsar.Message) int {
+	key := msg.OrderingKey()
+	if key == "" {
+		key = msg.Key()
+	}
+
+	if key == "" {
+		i := d.next
+		d.next = (d.next + 1) % len(d.workers)
+		return i
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(d.workers)))
+}
@@ -0,0 +1,110 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+)
+
+// stubConsumer 记录 ack 和 nack 的消费者
+type stubConsumer struct {
+	pulsar.Consumer
+
+	acked  atomic.Int64
+	nacked atomic.Int64
+}
+
+func (c *stubConsumer) Ack(pulsar.Message) error {
+	c.acked.Add(1)
+	return nil
+}
+
+func (c *stubConsumer) AckWithTxn(pulsar.Message, pulsar.Transaction) error {
+	c.acked.Add(1)
+	return nil
+}
+
+func (c *stubConsumer) AckIDList(ids []pulsar.MessageID) error {
+	c.acked.Add(int64(len(ids)))
+	return nil
+}
+
+func (c *stubConsumer) Nack(pulsar.Message) {
+	c.nacked.Add(1)
+}
+
+func TestDispatcherKeyOrdering(t *testing.T) {
+	stub := &stubConsumer{}
+	consumer := &Consumer{bus: &Pulbus{}, key: ConsumerKey{Topic: "orders", Subscription: "order-sub"}, Consumer: stub}
+
+	var (
+		mu       sync.Mutex
+		received = make(map[string][]int)
+		running  atomic.Int32
+		peak     atomic.Int32
+	)
+
+	handler := func(msg pulsar.Message) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+
+		seq, _ := strconv.Atoi(string(msg.Payload()))
+		mu.Lock()
+		received[msg.Key()] = append(received[msg.Key()], seq)
+		mu.Unlock()
+		return nil
+	}
+
+	options := newConsumerOptions(WithConsumerConcurrency(4), WithConsumerMaxInFlight(2))
+	messages := make(chan pulsar.ConsumerMessage)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- newDispatcher(consumer, MessageHandler(handler).withContext(), options).run(ctx, messages)
+	}()
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	for i := 0; i < 50; i++ {
+		messages <- pulsar.ConsumerMessage{Message: &stubMessage{
+			key:     keys[i%len(keys)],
+			payload: []byte(strconv.Itoa(i)),
+		}}
+	}
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// 取消后等待处理中的消息完成，已接收但未分发的消息被 nack
+	require.Equal(t, int64(50), stub.acked.Load()+stub.nacked.Load())
+	require.LessOrEqual(t, stub.nacked.Load(), int64(1))
+	require.LessOrEqual(t, peak.Load(), int32(2))
+
+	var total int
+	for _, key := range keys {
+		seqs := received[key]
+		total += len(seqs)
+		for i := 1; i < len(seqs); i++ {
+			require.Less(t, seqs[i-1], seqs[i])
+		}
+	}
+	require.Equal(t, int64(total), stub.acked.Load())
+}
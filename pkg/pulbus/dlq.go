@@ -0,0 +1,161 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+const (
+	// PropertyDeadLetterReason 死信原因属性名
+	PropertyDeadLetterReason = "DEAD_LETTER_REASON"
+
+	defaultNackBackoffMin    = time.Second
+	defaultNackBackoffMax    = 10 * time.Minute
+	defaultReplayIdleTimeout = 3 * time.Second
+	defaultDeadLetterTimeout = 30 * time.Second
+
+	// minNackBackoff 重投延迟下限，避免 Min 为 0 时退避无法增长导致立即重投
+	minNackBackoff = time.Millisecond
+)
+
+// 重投和死信相关的系统属性，重新投递时需要移除
+var deadLetterProperties = []string{
+	pulsar.SysPropertyDelayTime,
+	pulsar.SysPropertyRealTopic,
+	pulsar.SysPropertyRetryTopic,
+	pulsar.SysPropertyReconsumeTimes,
+	pulsar.SysPropertyOriginMessageID,
+	PropertyDeadLetterReason,
+}
+
+var _ pulsar.NackBackoffPolicy = (*ExponentialNackBackoff)(nil)
+
+// ExponentialNackBackoff 指数退避重投策略
+type ExponentialNackBackoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Next 返回第 redeliveryCount 次重投的延迟
+// Min 小于 minNackBackoff 时使用 minNackBackoff，Max 小于 Min 时使用 Min
+func (b *ExponentialNackBackoff) Next(redeliveryCount uint32) time.Duration {
+	d := max(b.Min, minNackBackoff)
+	limit := max(b.Max, d)
+	for i := uint32(0); i < redeliveryCount && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// retryDelay 计算发送到重试 Topic 的延迟
+func (o *ConsumerOptions) retryDelay(msg pulsar.Message) time.Duration {
+	policy := o.nackBackoff
+	if policy == nil {
+		policy = &ExponentialNackBackoff{Min: defaultNackBackoffMin, Max: defaultNackBackoffMax}
+	}
+
+	// 来自重试 Topic 的消息使用重新消费次数计算
+	count := msg.RedeliveryCount()
+	if s, ok := msg.Properties()[pulsar.SysPropertyReconsumeTimes]; ok {
+		if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+			count = uint32(n)
+		}
+	}
+
+	return policy.Next(count)
+}
+
+// sendToDeadLetter 直接投递消息到死信 Topic
+// 消费停止时不取消投递，但最长等待 defaultDeadLetterTimeout，避免死信 producer 阻塞时无法退出
+func (consumer *Consumer) sendToDeadLetter(ctx context.Context, msg pulsar.Message, reason error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultDeadLetterTimeout)
+	defer cancel()
+
+	properties := maps.Clone(msg.Properties())
+	if properties == nil {
+		properties = make(map[string]string)
+	}
+	properties[pulsar.SysPropertyRealTopic] = msg.Topic()
+	properties[pulsar.SysPropertyOriginMessageID] = msg.ID().String()
+	properties[PropertyDeadLetterReason] = reason.Error()
+
+	return consumer.bus.Send(ctx, DeadLetterTopic(consumer.key.Topic), func(message *pulsar.ProducerMessage) {
+		message.Key = msg.Key()
+		message.OrderingKey = msg.OrderingKey()
+		message.Payload = msg.Payload()
+		message.Properties = properties
+		message.EventTime = msg.EventTime()
+	})
+}
+
+// ReplayDLQ 将死信 Topic 中的消息重新投递到原 Topic
+// subscription 为死信 Topic 的订阅名，即启用 WithConsumerMaxRedeliveries 时的订阅名
+// 注意: 死信 Topic 由同一 Topic 的所有订阅共享，重新投递后原 Topic 的所有订阅都会收到消息
+// 在 defaultReplayIdleTimeout 内没有新消息时结束，返回重新投递的消息数量
+func (bus *Pulbus) ReplayDLQ(ctx context.Context, topic, subscription string) (n int, err error) {
+	topic = bus.ResolveTopic(topic)
+
+	var consumer pulsar.Consumer
+	consumer, err = bus.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:                       DeadLetterTopic(topic),
+		SubscriptionName:            subscription,
+		Type:                        pulsar.Shared,
+		SubscriptionInitialPosition: pulsar.SubscriptionPositionEarliest,
+	})
+	if err != nil {
+		return
+	}
+	defer consumer.Close()
+
+	for {
+		var msg pulsar.Message
+		msg, err = receiveWithIdleTimeout(ctx, consumer)
+		if err != nil {
+			// 空闲超时表示死信已全部处理
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				err = nil
+			}
+			return
+		}
+
+		properties := maps.Clone(msg.Properties())
+		for _, key := range deadLetterProperties {
+			delete(properties, key)
+		}
+
+		err = bus.Send(ctx, topic, func(message *pulsar.ProducerMessage) {
+			message.Key = msg.Key()
+			message.OrderingKey = msg.OrderingKey()
+			message.Payload = msg.Payload()
+			message.Properties = properties
+			message.EventTime = msg.EventTime()
+		})
+		if err != nil {
+			consumer.Nack(msg)
+			return
+		}
+
+		err = consumer.Ack(msg)
+		if err != nil {
+			return
+		}
+		n++
+	}
+}
+
+// receiveWithIdleTimeout 接收消息，超过 defaultReplayIdleTimeout 未收到消息返回 context.DeadlineExceeded
+func receiveWithIdleTimeout(ctx context.Context, consumer pulsar.Consumer) (pulsar.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultReplayIdleTimeout)
+	defer cancel()
+
+	return consumer.Receive(ctx)
+}
@@ -0,0 +1,273 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultDedupeTTL   = 24 * time.Hour  // 默认去重记录保留时间
+	DefaultDedupeLease = 5 * time.Minute // 默认处理中租约时间
+)
+
+// ErrDedupeInProgress 相同 key 的消息正在处理中，消息按处理失败重投，租约过期或处理完成后再判断是否重复
+var ErrDedupeInProgress = errors.New("相同去重 key 的消息正在处理中")
+
+// DedupeStore 去重存储
+// key 先以较短的租约占用，处理成功后标记为已完成并保留较长时间；
+// 进程在处理中崩溃时租约到期自动释放，重投的消息可以再次处理
+type DedupeStore interface {
+	// Acquire 占用 key 并在 ttl 后过期，key 已被占用或已完成且未过期时返回 false
+	Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Release 释放 key，消息处理失败时调用，以便重投的消息可以再次处理
+	Release(ctx context.Context, key string) error
+
+	// Complete 将 key 标记为已完成并在 ttl 后过期，key 不存在时直接写入
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+
+	// Completed 判断 key 是否已完成且未过期
+	Completed(ctx context.Context, key string) (bool, error)
+}
+
+// DedupeKeyFunc 从消息中提取去重 key，返回空字符串时不去重
+type DedupeKeyFunc func(msg pulsar.Message) string
+
+// MessageIDKey 使用消息 ID 作为去重 key
+// 重试 Topic 和死信重放的消息使用原始消息的 Topic 和 ID，与原始消息视为同一条消息
+func MessageIDKey(msg pulsar.Message) string {
+	properties := msg.Properties()
+	if id, ok := properties[pulsar.SysPropertyOriginMessageID]; ok {
+		return properties[pulsar.SysPropertyRealTopic] + "#" + id
+	}
+
+	if msg.ID() == nil {
+		return ""
+	}
+	return msg.Topic() + "#" + msg.ID().String()
+}
+
+// MessageKey 使用消息 Key 作为去重 key，适用于生产者使用业务唯一 ID 作为 Key 的场景
+func MessageKey(msg pulsar.Message) string {
+	return msg.Key()
+}
+
+// IdempotencyOptions 幂等中间件配置
+type IdempotencyOptions struct {
+	ttl      time.Duration
+	lease    time.Duration
+	keyFunc  DedupeKeyFunc
+	unscoped bool
+}
+
+// IdempotencyOption 幂等中间件选项
+type IdempotencyOption func(*IdempotencyOptions)
+
+// WithDedupeTTL 设置去重记录保留时间，默认 24h
+func WithDedupeTTL(ttl time.Duration) IdempotencyOption {
+	return func(o *IdempotencyOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithDedupeLease 设置处理中租约时间，默认 5m
+// handler 的 context 在租约到期时取消，租约应大于 handler 的最长处理时间
+func WithDedupeLease(lease time.Duration) IdempotencyOption {
+	return func(o *IdempotencyOptions) {
+		o.lease = lease
+	}
+}
+
+// WithDedupeKey 设置去重 key 提取函数，默认 MessageIDKey
+func WithDedupeKey(fn DedupeKeyFunc) IdempotencyOption {
+	return func(o *IdempotencyOptions) {
+		o.keyFunc = fn
+	}
+}
+
+// WithDedupeUnscoped 去重 key 不按订阅隔离，直接使用 WithDedupeKey 返回的 key
+// 适用于 key 已包含订阅信息，或需要在消费之外使用相同 key 操作去重存储的场景
+func WithDedupeUnscoped() IdempotencyOption {
+	return func(o *IdempotencyOptions) {
+		o.unscoped = true
+	}
+}
+
+// IdempotencyMiddleware 幂等消费中间件，已处理完成的重复消息直接 ack 且不调用 handler
+// 去重 key 默认按订阅隔离，同一消息在不同订阅中分别处理。
+// 处理前以租约占用 key，租约期间相同 key 的消息返回 ErrDedupeInProgress 并重投；
+// handler 成功后将 key 标记为已完成并保留 WithDedupeTTL，返回错误时释放 key，进程崩溃时租约到期后自动释放
+//
+// 使用示例:
+//
+//	store := NewMemoryDedupeStore()
+//	bus.Consume(ctx, "orders", "order-sub", handler,
+//	    WithConsumerMiddleware(IdempotencyMiddleware(store, WithDedupeKey(MessageKey))),
+//	)
+func IdempotencyMiddleware(store DedupeStore, opts ...IdempotencyOption) ConsumerMiddleware {
+	options := &IdempotencyOptions{
+		ttl:     DefaultDedupeTTL,
+		lease:   DefaultDedupeLease,
+		keyFunc: MessageIDKey,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return func(handler ContextHandler) ContextHandler {
+		return func(ctx context.Context, msg pulsar.Message) error {
+			key := options.keyFunc(msg)
+			if key == "" {
+				return handler(ctx, msg)
+			}
+
+			if consumer, ok := FromConsumerContext(ctx); ok && !options.unscoped {
+				key = consumer.Subscription + ":" + key
+			}
+
+			acquired, err := store.Acquire(ctx, key, options.lease)
+			if err != nil {
+				return err
+			}
+
+			if !acquired {
+				var completed bool
+				completed, err = store.Completed(ctx, key)
+				if err != nil {
+					return err
+				}
+				if !completed {
+					return ErrDedupeInProgress
+				}
+
+				zap.L().Info("[Pulsar Consumer] 跳过重复消息", append(messageFields(ctx, msg), zap.String("dedupe_key", key))...)
+				return nil
+			}
+
+			// 租约到期后其他消费者可以再次处理，handler 需在租约内完成
+			handlerCtx, cancel := context.WithTimeout(ctx, options.lease)
+			defer cancel()
+
+			err = handler(handlerCtx, msg)
+			if err != nil {
+				if releaseErr := store.Release(context.WithoutCancel(ctx), key); releaseErr != nil {
+					zap.L().Error("[Pulsar Consumer] 释放去重 key 失败", append(messageFields(ctx, msg), zap.String("dedupe_key", key), zap.Error(releaseErr))...)
+				}
+				return err
+			}
+
+			// 标记失败时返回错误，消息重投后 key 已被占用，租约到期后再次处理
+			if err = store.Complete(context.WithoutCancel(ctx), key, options.ttl); err != nil {
+				zap.L().Error("[Pulsar Consumer] 标记去重 key 完成失败", append(messageFields(ctx, msg), zap.String("dedupe_key", key), zap.Error(err))...)
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// MemoryDedupeStore 内存去重存储，仅适用于单实例部署和测试
+type MemoryDedupeStore struct {
+	mu      sync.Mutex
+	keys    map[string]memoryDedupeEntry
+	purgeAt time.Time
+}
+
+// memoryDedupeEntry 内存去重记录
+type memoryDedupeEntry struct {
+	expiresAt time.Time
+	completed bool
+}
+
+var _ DedupeStore = (*MemoryDedupeStore)(nil)
+
+// memoryDedupePurgeInterval 过期 key 清理间隔
+const memoryDedupePurgeInterval = time.Minute
+
+// NewMemoryDedupeStore 创建内存去重存储
+func NewMemoryDedupeStore() *MemoryDedupeStore {
+	return &MemoryDedupeStore{
+		keys: make(map[string]memoryDedupeEntry),
+	}
+}
+
+// Acquire 占用 key 并在 ttl 后过期
+func (s *MemoryDedupeStore) Acquire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.purge(now)
+
+	if entry, ok := s.keys[key]; ok && now.Before(entry.expiresAt) {
+		return false, nil
+	}
+
+	s.keys[key] = memoryDedupeEntry{expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Release 释放 key
+func (s *MemoryDedupeStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
+
+// Complete 将 key 标记为已完成并在 ttl 后过期
+func (s *MemoryDedupeStore) Complete(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key] = memoryDedupeEntry{expiresAt: time.Now().Add(ttl), completed: true}
+	return nil
+}
+
+// Completed 判断 key 是否已完成且未过期
+func (s *MemoryDedupeStore) Completed(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.keys[key]
+	return ok && entry.completed && time.Now().Before(entry.expiresAt), nil
+}
+
+// Len 返回未过期的 key 数量
+func (s *MemoryDedupeStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var n int
+	for _, entry := range s.keys {
+		if now.Before(entry.expiresAt) {
+			n++
+		}
+	}
+	return n
+}
+
+// purge 按间隔清理过期 key，调用方需持有锁
+func (s *MemoryDedupeStore) purge(now time.Time) {
+	if now.Before(s.purgeAt) {
+		return
+	}
+	s.purgeAt = now.Add(memoryDedupePurgeInterval)
+
+	for key, entry := range s.keys {
+		if !now.Before(entry.expiresAt) {
+			delete(s.keys, key)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	store := NewMemoryDedupeStore()
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.ConsumeContext(ctx, "orders", "order-sub", func(_ context.Context, msg pulsar.Message) error {
+			// 首次处理失败，释放 key 后重投的消息可再次处理
+			if msg.RedeliveryCount() == 0 && string(msg.Payload()) == "first" {
+				return errors.New("retry")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, string(msg.Payload()))
+			return nil
+		},
+			WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest),
+			WithConsumerNackBackoff(time.Millisecond, time.Millisecond),
+			WithConsumerMiddleware(IdempotencyMiddleware(store, WithDedupeKey(MessageKey))),
+		)
+	}()
+
+	require.NoError(t, bus.Send(ctx, "orders", WithProducerKey("order:1"), WithPayload([]byte("first"))))
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// 重复消息直接 ack，不调用 handler
+	require.NoError(t, bus.Send(ctx, "orders", WithProducerKey("order:1"), WithPayload([]byte("duplicate"))))
+	require.NoError(t, bus.Send(ctx, "orders", WithProducerKey("order:2"), WithPayload([]byte("second"))))
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	require.Equal(t, []string{"first", "second"}, received)
+	require.Equal(t, 2, store.Len())
+}
+
+func TestMemoryDedupeStore(t *testing.T) {
+	store := NewMemoryDedupeStore()
+	ctx := context.Background()
+
+	ok, err := store.Acquire(ctx, "k", 20*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.Acquire(ctx, "k", 20*time.Millisecond)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// 过期后可以再次占用
+	time.Sleep(30 * time.Millisecond)
+	ok, err = store.Acquire(ctx, "k", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// 完成后保留 ttl，不能再次占用
+	require.NoError(t, store.Complete(ctx, "k", time.Minute))
+	completed, err := store.Completed(ctx, "k")
+	require.NoError(t, err)
+	require.True(t, completed)
+
+	ok, err = store.Acquire(ctx, "k", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, store.Release(ctx, "k"))
+	require.Equal(t, 0, store.Len())
+}
+
+func TestIdempotencyMiddlewareLease(t *testing.T) {
+	store := NewMemoryDedupeStore()
+	ctx := context.Background()
+	msg := &stubMessage{key: "order:1"}
+
+	var (
+		calls   atomic.Int32
+		block   = make(chan struct{})
+		started = make(chan struct{})
+	)
+	defer close(block)
+
+	handler := IdempotencyMiddleware(store, WithDedupeKey(MessageKey), WithDedupeLease(50*time.Millisecond))(func(context.Context, pulsar.Message) error {
+		// 首次处理模拟进程崩溃，handler 不返回也不响应 context
+		if calls.Add(1) == 1 {
+			close(started)
+			<-block
+		}
+		return nil
+	})
+
+	go func() {
+		_ = handler(ctx, msg)
+	}()
+	<-started
+
+	// 租约期间重复消息重投而不是 ack
+	require.ErrorIs(t, handler(ctx, msg), ErrDedupeInProgress)
+	require.Equal(t, int32(1), calls.Load())
+
+	// 租约到期后重投的消息可以再次处理
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, handler(ctx, msg))
+	require.Equal(t, int32(2), calls.Load())
+
+	// 处理完成后重复消息直接 ack
+	require.NoError(t, handler(ctx, msg))
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestMessageIDKey(t *testing.T) {
+	msg := &stubMessage{properties: map[string]string{
+		pulsar.SysPropertyRealTopic:       "persistent://public/default/orders",
+		pulsar.SysPropertyOriginMessageID: "1:2:0",
+	}}
+	require.Equal(t, "persistent://public/default/orders#1:2:0", MessageIDKey(msg))
+
+	// 无消息 ID 时不去重
+	require.Empty(t, MessageIDKey(&stubMessage{}))
+}
+
+// stubResult 仅用于测试的执行结果
+type stubResult int64
+
+func (r stubResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r stubResult) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+// stubDriver 记录执行语句的 ent 驱动，使用 map 模拟去重表，值表示是否已完成
+type stubDriver struct {
+	dialect.Driver
+
+	queries []string
+	keys    map[string]bool
+}
+
+// stubRows 仅返回是否存在记录的结果集
+type stubRows struct {
+	sql.ColumnScanner
+
+	next bool
+}
+
+func (r *stubRows) Next() bool {
+	next := r.next
+	r.next = false
+	return next
+}
+
+func (r *stubRows) Err() error {
+	return nil
+}
+
+func (r *stubRows) Close() error {
+	return nil
+}
+
+func (d *stubDriver) Dialect() string {
+	return dialect.Postgres
+}
+
+func (d *stubDriver) Exec(_ context.Context, query string, args, v any) error {
+	d.queries = append(d.queries, query)
+
+	var n int64
+	key, _ := args.([]any)[0].(string)
+	switch {
+	case strings.HasPrefix(query, "INSERT") && strings.Contains(query, "DO UPDATE"):
+		d.keys[key] = true
+		n = 1
+	case strings.HasPrefix(query, "INSERT"):
+		if _, ok := d.keys[key]; !ok {
+			d.keys[key] = false
+			n = 1
+		}
+	case strings.HasPrefix(query, "DELETE") && !strings.Contains(query, "expires_at"):
+		delete(d.keys, key)
+		n = 1
+	}
+
+	*v.(*stdsql.Result) = stubResult(n)
+	return nil
+}
+
+func (d *stubDriver) Query(_ context.Context, query string, args, v any) error {
+	d.queries = append(d.queries, query)
+
+	key, _ := args.([]any)[0].(string)
+	*v.(*sql.Rows) = sql.Rows{ColumnScanner: &stubRows{next: d.keys[key]}}
+	return nil
+}
+
+func TestSQLDedupeStore(t *testing.T) {
+	drv := &stubDriver{keys: make(map[string]bool)}
+	store := NewSQLDedupeStore(drv, WithDedupeTable("dedupe"))
+	ctx := context.Background()
+
+	ok, err := store.Acquire(ctx, "k", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = store.Acquire(ctx, "k", time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, store.Release(ctx, "k"))
+
+	ok, err = store.Acquire(ctx, "k", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	completed, err := store.Completed(ctx, "k")
+	require.NoError(t, err)
+	require.False(t, completed)
+
+	require.NoError(t, store.Complete(ctx, "k", time.Hour))
+	completed, err = store.Completed(ctx, "k")
+	require.NoError(t, err)
+	require.True(t, completed)
+
+	require.Equal(t, `DELETE FROM "dedupe" WHERE "dedupe_key" = $1 AND "expires_at" <= $2`, drv.queries[0])
+	require.Equal(t, `INSERT INTO "dedupe" ("dedupe_key", "expires_at") VALUES ($1, $2) ON CONFLICT ("dedupe_key") DO NOTHING`, drv.queries[1])
+	require.Equal(t, `DELETE FROM "dedupe" WHERE "dedupe_key" = $1`, drv.queries[4])
+	require.Equal(t, `SELECT "dedupe_key" FROM "dedupe" WHERE "dedupe_key" = $1 AND "completed" AND "expires_at" > $2`, drv.queries[7])
+	require.Equal(t, `INSERT INTO "dedupe" ("dedupe_key", "expires_at", "completed") VALUES ($1, $2, $3) ON CONFLICT ("dedupe_key") DO UPDATE SET "dedupe_key" = "excluded"."dedupe_key", "expires_at" = "excluded"."expires_at", "completed" = "excluded"."completed"`, drv.queries[8])
+}
@@ -0,0 +1,142 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"nexis.run/nexa/kit/graceful"
+)
+
+var _ graceful.Gracefully = (*Pulbus)(nil)
+
+// DrainError 停止时未能在截止时间内处理完消息的消费者
+type DrainError struct {
+	Consumers []ConsumerKey
+	Err       error
+}
+
+func (e *DrainError) Error() string {
+	names := make([]string, len(e.Consumers))
+	for i, key := range e.Consumers {
+		names[i] = key.Topic + "@" + key.Subscription
+	}
+	return fmt.Sprintf("消费者未能处理完消息 [%s]: %v", strings.Join(names, ", "), e.Err)
+}
+
+func (e *DrainError) Unwrap() error {
+	return e.Err
+}
+
+// Start 实现 graceful.Gracefully，连接在 New 时已建立，消费由 Consume 等方法启动，无需额外操作
+func (bus *Pulbus) Start() {}
+
+// Stop 实现 graceful.Gracefully，调用 Shutdown 并记录未能处理完消息的消费者
+//
+// 使用示例:
+//
+//	graceful.Run(bus, graceful.WithTimeout(30*time.Second))
+func (bus *Pulbus) Stop(ctx context.Context) {
+	err := bus.Shutdown(ctx)
+	if err == nil {
+		zap.L().Info("[Pulsar] 消息总线已停止")
+		return
+	}
+
+	var drainErr *DrainError
+	if errors.As(err, &drainErr) {
+		for _, key := range drainErr.Consumers {
+			zap.L().Error("[Pulsar] 消费者未能处理完消息", zap.String("topic", key.Topic), zap.String("subscription", key.Subscription))
+		}
+	}
+	zap.L().Error("[Pulsar] 消息总线停止失败", zap.Error(err))
+}
+
+// Shutdown 优雅停止消息总线
+// 停止接收新消息，等待处理中的消息完成并刷新 producer 缓冲的消息，最后关闭所有连接
+// ctx 截止前仍未处理完消息的消费者以 DrainError 返回，连接仍会关闭
+func (bus *Pulbus) Shutdown(ctx context.Context) error {
+	bus.stop()
+
+	// 等待消费循环退出，循环退出前会处理完已接收的消息
+	var undrained []ConsumerKey
+	bus.consumers.Range(func(_, value any) bool {
+		consumer := value.(*Consumer)
+
+		drained := make(chan struct{})
+		go func() {
+			consumer.running.Wait()
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			undrained = append(undrained, consumer.key)
+		}
+		return true
+	})
+
+	// 刷新 producer 缓冲的消息
+	var errs []error
+	bus.producers.Range(func(_, value any) bool {
+		if err := value.(*Producer).FlushWithCtx(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("刷新 producer 失败 [%s]: %w", value.(*Producer).Topic(), err))
+		}
+		return true
+	})
+
+	bus.close()
+
+	if len(undrained) > 0 {
+		errs = append(errs, &DrainError{Consumers: undrained, Err: ctx.Err()})
+	}
+	return errors.Join(errs...)
+}
+
+// stop 停止接收新消息，消费方法返回 ErrBusClosed
+func (bus *Pulbus) stop() {
+	bus.lifecycle.Lock()
+	defer bus.lifecycle.Unlock()
+
+	if bus.stopped {
+		return
+	}
+	bus.stopped = true
+	close(bus.done)
+
+	bus.replies.close()
+}
+
+// begin 开始消费，返回在 ctx 取消或总线停止时取消的 context，消费结束后需调用返回的函数
+// 总线已停止时返回 ErrBusClosed
+func (bus *Pulbus) begin(ctx context.Context, consumer *Consumer) (context.Context, func(), error) {
+	bus.lifecycle.RLock()
+	defer bus.lifecycle.RUnlock()
+
+	if bus.stopped {
+		return nil, nil, ErrBusClosed
+	}
+	consumer.running.Add(1)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-bus.done:
+			cancel(ErrBusClosed)
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		cancel(nil)
+		consumer.running.Done()
+	}, nil
+}
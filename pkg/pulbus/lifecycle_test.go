@@ -0,0 +1,73 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	bus := NewMemory()
+
+	started := make(chan struct{})
+	var handled atomic.Bool
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.ConsumeContext(context.Background(), "orders", "order-sub", func(context.Context, pulsar.Message) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			handled.Store(true)
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	require.NoError(t, bus.Send(context.Background(), "orders", WithPayload([]byte("order"))))
+	<-started
+
+	// 等待处理中的消息完成
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, bus.Shutdown(ctx))
+	require.True(t, handled.Load())
+	require.ErrorIs(t, <-done, ErrBusClosed)
+
+	// 停止后不再开始消费
+	err := bus.Consume(context.Background(), "orders", "order-sub", func(pulsar.Message) error { return nil })
+	require.ErrorIs(t, err, ErrBusClosed)
+	require.NoError(t, bus.Close())
+}
+
+func TestShutdownDrainTimeout(t *testing.T) {
+	bus := NewMemory()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		_ = bus.ConsumeContext(context.Background(), "orders", "order-sub", func(context.Context, pulsar.Message) error {
+			close(started)
+			<-release
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	require.NoError(t, bus.Send(context.Background(), "orders", WithPayload([]byte("order"))))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := bus.Shutdown(ctx)
+
+	var drainErr *DrainError
+	require.ErrorAs(t, err, &drainErr)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, []ConsumerKey{{Topic: "orders", Subscription: "order-sub"}}, drainErr.Consumers)
+}
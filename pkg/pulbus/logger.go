@@ -0,0 +1,82 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"github.com/apache/pulsar-client-go/pulsar/log"
+	"go.uber.org/zap"
+)
+
+// zapLogger 将 pulsar client 的日志输出到 zap
+type zapLogger struct {
+	logger *zap.SugaredLogger
+}
+
+var _ log.Logger = (*zapLogger)(nil)
+
+// NewZapLogger 创建 pulsar client 使用的 zap 日志，logger 为空时使用 zap.L()
+func NewZapLogger(logger *zap.Logger) log.Logger {
+	if logger == nil {
+		logger = zap.L()
+	}
+	return &zapLogger{logger: logger.Named("pulsar").Sugar()}
+}
+
+// with 附加日志字段
+func (l *zapLogger) with(fields log.Fields) *zapLogger {
+	args := make([]any, 0, len(fields)*2)
+	for k, v := range fields {
+		args = append(args, k, v)
+	}
+	return &zapLogger{logger: l.logger.With(args...)}
+}
+
+func (l *zapLogger) SubLogger(fields log.Fields) log.Logger {
+	return l.with(fields)
+}
+
+func (l *zapLogger) WithFields(fields log.Fields) log.Entry {
+	return l.with(fields)
+}
+
+func (l *zapLogger) WithField(name string, value any) log.Entry {
+	return &zapLogger{logger: l.logger.With(name, value)}
+}
+
+func (l *zapLogger) WithError(err error) log.Entry {
+	return &zapLogger{logger: l.logger.With(zap.Error(err))}
+}
+
+func (l *zapLogger) Debug(args ...any) {
+	l.logger.Debug(args...)
+}
+
+func (l *zapLogger) Info(args ...any) {
+	l.logger.Info(args...)
+}
+
+func (l *zapLogger) Warn(args ...any) {
+	l.logger.Warn(args...)
+}
+
+func (l *zapLogger) Error(args ...any) {
+	l.logger.Error(args...)
+}
+
+func (l *zapLogger) Debugf(format string, args ...any) {
+	l.logger.Debugf(format, args...)
+}
+
+func (l *zapLogger) Infof(format string, args ...any) {
+	l.logger.Infof(format, args...)
+}
+
+func (l *zapLogger) Warnf(format string, args ...any) {
+	l.logger.Warnf(format, args...)
+}
+
+func (l *zapLogger) Errorf(format string, args ...any) {
+	l.logger.Errorf(format, args...)
+}
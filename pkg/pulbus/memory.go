@@ -0,0 +1,915 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMemoryNackRedeliveryDelay = time.Minute // 与 pulsar 默认值一致
+	defaultMemoryReceiverQueueSize   = 1000
+)
+
+var (
+	ErrNotSupported                 = errors.New("内存消息总线不支持该操作")
+	ErrMemoryTransactionUnsupported = fmt.Errorf("%w: 事务", ErrNotSupported)
+	ErrMemorySeekUnsupported        = fmt.Errorf("%w: Seek", ErrNotSupported)
+	ErrMemoryExclusiveSubscription  = errors.New("独占订阅已有消费者")
+	ErrMemoryClientClosed           = errors.New("内存消息总线已关闭")
+	ErrMemoryConsumerClosed         = errors.New("内存消费者已关闭")
+	ErrMemoryInvalidMessageID       = errors.New("无效的内存消息 ID")
+)
+
+var (
+	_ pulsar.Client   = (*memoryClient)(nil)
+	_ pulsar.Producer = (*memoryProducer)(nil)
+	_ pulsar.Consumer = (*memoryConsumer)(nil)
+)
+
+// NewMemory 创建基于内存的 Pulbus，用于单元测试，无需连接 broker
+// 支持 Shared / Failover / Exclusive / KeyShared 订阅语义、Nack 重投、延迟投递、死信和重试 Topic
+// Schema 仅用于编解码，不检查兼容性
+// 不支持事务、Reader、TableView 和分区 Topic，调用时返回 ErrNotSupported，WithAdmin 配置会被忽略
+//
+// 使用示例:
+//
+//	var bus Bus = NewMemory()
+//	go bus.Consume(ctx, "orders", "order-sub", handler, WithConsumerNackBackoff(10*time.Millisecond, time.Second))
+//	_ = bus.Send(ctx, "orders", WithPayload(data))
+func NewMemory(opts ...Option) *Pulbus {
+	bus := &Pulbus{
+		clientOptions: pulsar.ClientOptions{
+			URL: "memory://",
+		},
+		done: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(bus)
+	}
+
+	bus.client = newMemoryClient()
+	return bus
+}
+
+// memoryTopicName 规范化 Topic 名称
+func memoryTopicName(topic string) string {
+	return ParseTopic(topic).FullName()
+}
+
+// memoryClient 内存 pulsar client
+type memoryClient struct {
+	mu        sync.Mutex
+	topics    map[string]*memoryTopic
+	consumers map[*memoryConsumer]struct{}
+	ledgers   int64
+	closed    bool
+}
+
+func newMemoryClient() *memoryClient {
+	return &memoryClient{
+		topics:    make(map[string]*memoryTopic),
+		consumers: make(map[*memoryConsumer]struct{}),
+	}
+}
+
+// topic 获取 Topic，不存在时创建并订阅匹配的正则消费者
+func (c *memoryClient) topic(name string) *memoryTopic {
+	name = memoryTopicName(name)
+
+	c.mu.Lock()
+	t, ok := c.topics[name]
+	var matched []*memoryConsumer
+	if !ok {
+		c.ledgers++
+		t = &memoryTopic{
+			name:          name,
+			ledgerID:      c.ledgers,
+			subscriptions: make(map[string]*memorySubscription),
+		}
+		c.topics[name] = t
+
+		for mc := range c.consumers {
+			if mc.pattern != nil && mc.pattern.MatchString(name) {
+				matched = append(matched, mc)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for _, mc := range matched {
+		_ = mc.subscribe(t)
+	}
+	return t
+}
+
+func (c *memoryClient) CreateProducer(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrMemoryClientClosed
+	}
+
+	name := options.Name
+	if name == "" {
+		name = "memory-producer-" + uuid.NewString()
+	}
+
+	return &memoryProducer{
+		client: c,
+		topic:  c.topic(options.Topic),
+		name:   name,
+		schema: options.Schema,
+	}, nil
+}
+
+func (c *memoryClient) Subscribe(options pulsar.ConsumerOptions) (pulsar.Consumer, error) {
+	mc := &memoryConsumer{
+		client:  c,
+		options: options,
+		ch:      options.MessageChannel,
+		closed:  make(chan struct{}),
+	}
+
+	if mc.options.Name == "" {
+		mc.options.Name = "memory-consumer-" + uuid.NewString()
+	}
+
+	if mc.ch == nil {
+		size := options.ReceiverQueueSize
+		if size <= 0 {
+			size = defaultMemoryReceiverQueueSize
+		}
+		mc.ch = make(chan pulsar.ConsumerMessage, size)
+	}
+
+	topics := options.Topics
+	if options.Topic != "" {
+		topics = append([]string{options.Topic}, topics...)
+	}
+
+	// 启用重试时同时订阅重试 Topic
+	if options.RetryEnable && options.DLQ != nil && options.DLQ.RetryLetterTopic != "" {
+		topics = append(topics, options.DLQ.RetryLetterTopic)
+	}
+
+	// 死信 Topic 创建初始订阅以保留死信
+	if options.DLQ != nil && options.DLQ.DeadLetterTopic != "" && options.DLQ.InitialSubscriptionName != "" {
+		c.topic(options.DLQ.DeadLetterTopic).subscription(options.DLQ.InitialSubscriptionName, pulsar.Shared, pulsar.SubscriptionPositionEarliest)
+	}
+
+	if options.TopicsPattern != "" {
+		pattern := options.TopicsPattern
+		if !strings.Contains(pattern, "://") {
+			pattern = "persistent://public/default/" + pattern
+		}
+
+		var err error
+		mc.pattern, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrMemoryClientClosed
+	}
+	c.consumers[mc] = struct{}{}
+	if mc.pattern != nil {
+		for name := range c.topics {
+			if mc.pattern.MatchString(name) {
+				topics = append(topics, name)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for _, name := range topics {
+		if err := mc.subscribe(c.topic(name)); err != nil {
+			mc.Close()
+			return nil, err
+		}
+	}
+
+	return mc, nil
+}
+
+func (c *memoryClient) CreateReader(pulsar.ReaderOptions) (pulsar.Reader, error) {
+	return nil, fmt.Errorf("%w: Reader", ErrNotSupported)
+}
+
+func (c *memoryClient) CreateTableView(pulsar.TableViewOptions) (pulsar.TableView, error) {
+	return nil, fmt.Errorf("%w: TableView", ErrNotSupported)
+}
+
+func (c *memoryClient) NewTransaction(time.Duration) (pulsar.Transaction, error) {
+	return nil, ErrMemoryTransactionUnsupported
+}
+
+func (c *memoryClient) TopicPartitions(topic string) ([]string, error) {
+	return []string{memoryTopicName(topic)}, nil
+}
+
+func (c *memoryClient) Close() {
+	c.mu.Lock()
+	c.closed = true
+	consumers := slices.Collect(maps.Keys(c.consumers))
+	topics := slices.Collect(maps.Values(c.topics))
+	c.mu.Unlock()
+
+	for _, mc := range consumers {
+		mc.Close()
+	}
+
+	for _, t := range topics {
+		t.close()
+	}
+}
+
+// memoryTopic 内存 Topic，保留尚未被所有订阅确认的消息，新订阅可以从最早保留的消息开始消费
+// 没有订阅时保留全部消息
+type memoryTopic struct {
+	name     string
+	ledgerID int64
+
+	mu            sync.Mutex
+	entries       int64
+	log           []*memoryMessage
+	subscriptions map[string]*memorySubscription
+}
+
+// publish 发布消息到所有订阅
+func (t *memoryTopic) publish(m *memoryMessage) pulsar.MessageID {
+	t.mu.Lock()
+	t.entries++
+	m.topic = t.name
+	m.id = &memoryMessageID{ledgerID: t.ledgerID, entryID: t.entries, msg: m}
+	t.log = append(t.log, m)
+
+	// 持有 Topic 锁入队，避免 trim 时消息已写入日志但尚未入队
+	for _, s := range t.subscriptions {
+		s.enqueue(m.clone(s))
+	}
+	t.mu.Unlock()
+
+	return m.id
+}
+
+// trim 移除已被所有订阅确认的消息
+func (t *memoryTopic) trim() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.subscriptions) == 0 {
+		return
+	}
+
+	position := int64(math.MaxInt64)
+	for _, s := range t.subscriptions {
+		position = min(position, s.position())
+	}
+
+	i, _ := slices.BinarySearchFunc(t.log, position, func(m *memoryMessage, id int64) int {
+		return int(m.id.entryID - id)
+	})
+	t.log = slices.Delete(t.log, 0, i)
+}
+
+// subscription 获取订阅，不存在时按初始位置创建
+func (t *memoryTopic) subscription(name string, subscriptionType pulsar.SubscriptionType, position pulsar.SubscriptionInitialPosition) *memorySubscription {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s, ok := t.subscriptions[name]; ok {
+		return s
+	}
+
+	s := &memorySubscription{
+		topic:            t,
+		name:             name,
+		subscriptionType: subscriptionType,
+		unacked:          make(map[*memoryMessage]*memoryConsumer),
+		nacked:           make(map[*memoryMessage]struct{}),
+		notify:           make(chan struct{}, 1),
+		done:             make(chan struct{}),
+	}
+
+	if position == pulsar.SubscriptionPositionEarliest {
+		for _, m := range t.log {
+			s.backlog = append(s.backlog, m.clone(s))
+		}
+	}
+
+	t.subscriptions[name] = s
+	go s.run()
+
+	return s
+}
+
+// unsubscribe 删除订阅
+func (t *memoryTopic) unsubscribe(s *memorySubscription) {
+	t.mu.Lock()
+	delete(t.subscriptions, s.name)
+	t.mu.Unlock()
+
+	s.close()
+	t.trim()
+}
+
+func (t *memoryTopic) close() {
+	t.mu.Lock()
+	subs := slices.Collect(maps.Values(t.subscriptions))
+	t.mu.Unlock()
+
+	for _, s := range subs {
+		s.close()
+	}
+}
+
+// memorySubscription 内存订阅，按订阅类型将消息分发给消费者
+type memorySubscription struct {
+	topic            *memoryTopic
+	name             string
+	subscriptionType pulsar.SubscriptionType
+
+	mu        sync.Mutex
+	consumers []*memoryConsumer
+	backlog   []*memoryMessage // 待投递消息，按 entryID 排序
+	unacked   map[*memoryMessage]*memoryConsumer
+	nacked    map[*memoryMessage]struct{} // 等待重投的消息
+	next      int                         // Shared 订阅轮询位置
+
+	notify    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// attach 添加消费者
+func (s *memorySubscription) attach(mc *memoryConsumer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscriptionType == pulsar.Exclusive && len(s.consumers) > 0 {
+		return ErrMemoryExclusiveSubscription
+	}
+
+	s.consumers = append(s.consumers, mc)
+	s.signal()
+	return nil
+}
+
+// detach 移除消费者，未 ack 的消息重新投递给其他消费者
+func (s *memorySubscription) detach(mc *memoryConsumer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.consumers = slices.DeleteFunc(s.consumers, func(c *memoryConsumer) bool {
+		return c == mc
+	})
+
+	for m, c := range s.unacked {
+		if c == mc {
+			delete(s.unacked, m)
+			s.insert(m)
+		}
+	}
+	s.signal()
+}
+
+// enqueue 添加待投递消息
+func (s *memorySubscription) enqueue(m *memoryMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.insert(m)
+	s.signal()
+}
+
+// insert 按 entryID 顺序插入待投递消息，调用方需持有锁
+func (s *memorySubscription) insert(m *memoryMessage) {
+	i, _ := slices.BinarySearchFunc(s.backlog, m.id.entryID, func(e *memoryMessage, id int64) int {
+		return int(e.id.entryID - id)
+	})
+	s.backlog = slices.Insert(s.backlog, i, m)
+}
+
+// position 返回最早未确认的消息 entryID，全部确认时返回 math.MaxInt64
+func (s *memorySubscription) position() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	position := int64(math.MaxInt64)
+	if len(s.backlog) > 0 {
+		position = s.backlog[0].id.entryID
+	}
+	for m := range s.unacked {
+		position = min(position, m.id.entryID)
+	}
+	for m := range s.nacked {
+		position = min(position, m.id.entryID)
+	}
+	return position
+}
+
+// signal 通知分发协程，调用方需持有锁
+func (s *memorySubscription) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take 取出下一条可投递的消息及目标消费者，没有可投递消息时返回下一条延迟消息的等待时间
+func (s *memorySubscription) take() (*memoryMessage, *memoryConsumer, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.consumers) == 0 {
+		return nil, nil, 0
+	}
+
+	// 延迟投递仅对 Shared 和 KeyShared 订阅生效
+	delayed := s.subscriptionType == pulsar.Shared || s.subscriptionType == pulsar.KeyShared
+
+	now := time.Now()
+	var wait time.Duration
+	for i, m := range s.backlog {
+		if delayed && m.deliverAt.After(now) {
+			if d := m.deliverAt.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		c := s.pick(m)
+		s.backlog = slices.Delete(s.backlog, i, i+1)
+		s.unacked[m] = c
+		return m, c, 0
+	}
+
+	return nil, nil, wait
+}
+
+// pick 按订阅类型选择消费者，调用方需持有锁
+func (s *memorySubscription) pick(m *memoryMessage) *memoryConsumer {
+	switch s.subscriptionType {
+	case pulsar.Shared:
+		c := s.consumers[s.next%len(s.consumers)]
+		s.next++
+		return c
+	case pulsar.KeyShared:
+		key := m.orderingKey
+		if key == "" {
+			key = m.key
+		}
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		return s.consumers[h.Sum32()%uint32(len(s.consumers))]
+	default:
+		// Exclusive 和 Failover 订阅由第一个消费者接收全部消息
+		return s.consumers[0]
+	}
+}
+
+// run 分发协程
+func (s *memorySubscription) run() {
+	for {
+		m, c, wait := s.take()
+		if m == nil {
+			var timer *time.Timer
+			var timeout <-chan time.Time
+			if wait > 0 {
+				timer = time.NewTimer(wait)
+				timeout = timer.C
+			}
+
+			select {
+			case <-s.notify:
+			case <-timeout:
+			case <-s.done:
+			}
+
+			if timer != nil {
+				timer.Stop()
+			}
+
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			continue
+		}
+
+		// 超过最大投递次数进入死信 Topic
+		if c.deadLetter(m) {
+			continue
+		}
+
+		// 使用接收消费者的 Schema 解码
+		m.schema = c.options.Schema
+
+		select {
+		case c.ch <- pulsar.ConsumerMessage{Consumer: c, Message: m}:
+		case <-c.closed:
+			// 消费者关闭时已将未 ack 的消息重新入队
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// ack 确认消息
+func (s *memorySubscription) ack(m *memoryMessage) {
+	s.mu.Lock()
+	delete(s.unacked, m)
+	s.mu.Unlock()
+
+	s.topic.trim()
+}
+
+// ackCumulative 确认消费者在 entryID 之前（含）的所有消息
+func (s *memorySubscription) ackCumulative(mc *memoryConsumer, entryID int64) {
+	s.mu.Lock()
+	for m, c := range s.unacked {
+		if c == mc && m.id.entryID <= entryID {
+			delete(s.unacked, m)
+		}
+	}
+	s.mu.Unlock()
+
+	s.topic.trim()
+}
+
+// nack 在 delay 后重新投递消息
+func (s *memorySubscription) nack(m *memoryMessage, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.unacked[m]; !ok {
+		return
+	}
+	delete(s.unacked, m)
+
+	// 重投使用新的消息实例
+	r := m.clone(s)
+	r.redeliveryCount++
+	s.nacked[r] = struct{}{}
+
+	time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.nacked, r)
+		s.insert(r)
+		s.signal()
+	})
+}
+
+func (s *memorySubscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// memoryProducer 内存 producer
+type memoryProducer struct {
+	client *memoryClient
+	topic  *memoryTopic
+	name   string
+	schema pulsar.Schema
+
+	mu         sync.Mutex
+	sequenceID int64
+}
+
+func (p *memoryProducer) Topic() string {
+	return p.topic.name
+}
+
+func (p *memoryProducer) Name() string {
+	return p.name
+}
+
+func (p *memoryProducer) Send(_ context.Context, msg *pulsar.ProducerMessage) (pulsar.MessageID, error) {
+	if msg.Transaction != nil {
+		return nil, ErrMemoryTransactionUnsupported
+	}
+
+	payload := msg.Payload
+	if payload == nil && msg.Value != nil {
+		if p.schema == nil {
+			return nil, ErrMemorySchemaUnsupported
+		}
+
+		var err error
+		payload, err = p.schema.Encode(msg.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	p.sequenceID++
+	if msg.SequenceID != nil {
+		p.sequenceID = *msg.SequenceID
+	}
+	p.mu.Unlock()
+
+	m := &memoryMessage{
+		producerName: p.name,
+		properties:   maps.Clone(msg.Properties),
+		payload:      payload,
+		publishTime:  time.Now(),
+		eventTime:    msg.EventTime,
+		key:          msg.Key,
+		orderingKey:  msg.OrderingKey,
+		deliverAt:    msg.DeliverAt,
+	}
+	if msg.DeliverAfter > 0 {
+		m.deliverAt = m.publishTime.Add(msg.DeliverAfter)
+	}
+
+	return p.topic.publish(m), nil
+}
+
+func (p *memoryProducer) SendAsync(ctx context.Context, msg *pulsar.ProducerMessage, callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)) {
+	id, err := p.Send(ctx, msg)
+	callback(id, msg, err)
+}
+
+func (p *memoryProducer) LastSequenceID() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.sequenceID
+}
+
+func (p *memoryProducer) Flush() error {
+	return nil
+}
+
+func (p *memoryProducer) FlushWithCtx(context.Context) error {
+	return nil
+}
+
+func (p *memoryProducer) Close() {}
+
+// memoryConsumer 内存 consumer
+type memoryConsumer struct {
+	client  *memoryClient
+	options pulsar.ConsumerOptions
+	pattern *regexp.Regexp
+
+	mu   sync.Mutex
+	subs []*memorySubscription
+
+	ch        chan pulsar.ConsumerMessage
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// subscribe 订阅 Topic
+func (mc *memoryConsumer) subscribe(t *memoryTopic) error {
+	s := t.subscription(mc.options.SubscriptionName, mc.options.Type, mc.options.SubscriptionInitialPosition)
+	if err := s.attach(mc); err != nil {
+		return err
+	}
+
+	mc.mu.Lock()
+	mc.subs = append(mc.subs, s)
+	mc.mu.Unlock()
+	return nil
+}
+
+// deadLetter 超过最大投递次数时投递到死信 Topic 并 ack
+func (mc *memoryConsumer) deadLetter(m *memoryMessage) bool {
+	dlq := mc.options.DLQ
+	if dlq == nil || dlq.MaxDeliveries == 0 || dlq.DeadLetterTopic == "" || m.redeliveryCount < dlq.MaxDeliveries {
+		return false
+	}
+
+	properties := maps.Clone(m.properties)
+	if properties == nil {
+		properties = make(map[string]string)
+	}
+	properties[pulsar.SysPropertyRealTopic] = m.topic
+	properties[pulsar.SysPropertyOriginMessageID] = m.id.String()
+
+	mc.client.topic(dlq.DeadLetterTopic).publish(&memoryMessage{
+		producerName: mc.options.Name,
+		properties:   properties,
+		payload:      m.payload,
+		publishTime:  time.Now(),
+		eventTime:    m.eventTime,
+		key:          m.key,
+		orderingKey:  m.orderingKey,
+	})
+
+	m.sub.ack(m)
+	return true
+}
+
+// nackDelay 计算 nack 重投延迟
+func (mc *memoryConsumer) nackDelay(m *memoryMessage) time.Duration {
+	if mc.options.NackBackoffPolicy != nil {
+		return mc.options.NackBackoffPolicy.Next(m.redeliveryCount)
+	}
+	if mc.options.NackRedeliveryDelay != 0 {
+		return max(mc.options.NackRedeliveryDelay, 0)
+	}
+	return defaultMemoryNackRedeliveryDelay
+}
+
+func (mc *memoryConsumer) Subscription() string {
+	return mc.options.SubscriptionName
+}
+
+func (mc *memoryConsumer) Name() string {
+	return mc.options.Name
+}
+
+func (mc *memoryConsumer) Unsubscribe() error {
+	mc.mu.Lock()
+	subs := slices.Clone(mc.subs)
+	mc.mu.Unlock()
+
+	mc.Close()
+
+	for _, s := range subs {
+		s.topic.unsubscribe(s)
+	}
+	return nil
+}
+
+func (mc *memoryConsumer) UnsubscribeForce() error {
+	return mc.Unsubscribe()
+}
+
+func (mc *memoryConsumer) GetLastMessageIDs() ([]pulsar.TopicMessageID, error) {
+	return nil, nil
+}
+
+func (mc *memoryConsumer) Receive(ctx context.Context) (pulsar.Message, error) {
+	select {
+	case cm := <-mc.ch:
+		return cm.Message, nil
+	case <-mc.closed:
+		return nil, ErrMemoryConsumerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (mc *memoryConsumer) Chan() <-chan pulsar.ConsumerMessage {
+	return mc.ch
+}
+
+func (mc *memoryConsumer) Ack(msg pulsar.Message) error {
+	return mc.AckID(msg.ID())
+}
+
+func (mc *memoryConsumer) AckID(id pulsar.MessageID) error {
+	mid, ok := id.(*memoryMessageID)
+	if !ok {
+		return ErrMemoryInvalidMessageID
+	}
+
+	mid.msg.sub.ack(mid.msg)
+	return nil
+}
+
+func (mc *memoryConsumer) AckIDList(ids []pulsar.MessageID) error {
+	for _, id := range ids {
+		if err := mc.AckID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mc *memoryConsumer) AckWithTxn(pulsar.Message, pulsar.Transaction) error {
+	return ErrMemoryTransactionUnsupported
+}
+
+func (mc *memoryConsumer) AckCumulative(msg pulsar.Message) error {
+	return mc.AckIDCumulative(msg.ID())
+}
+
+func (mc *memoryConsumer) AckIDCumulative(id pulsar.MessageID) error {
+	mid, ok := id.(*memoryMessageID)
+	if !ok {
+		return ErrMemoryInvalidMessageID
+	}
+
+	mid.msg.sub.ackCumulative(mc, mid.entryID)
+	return nil
+}
+
+func (mc *memoryConsumer) ReconsumeLater(msg pulsar.Message, delay time.Duration) {
+	mc.ReconsumeLaterWithCustomProperties(msg, nil, delay)
+}
+
+// ReconsumeLaterWithCustomProperties 与 pulsar 一致: 发送到重试 Topic，超过最大投递次数发送到死信 Topic
+func (mc *memoryConsumer) ReconsumeLaterWithCustomProperties(msg pulsar.Message, customProperties map[string]string, delay time.Duration) {
+	dlq := mc.options.DLQ
+	if !mc.options.RetryEnable || dlq == nil {
+		return
+	}
+
+	properties := maps.Clone(msg.Properties())
+	if properties == nil {
+		properties = make(map[string]string)
+	}
+	maps.Copy(properties, customProperties)
+
+	reconsumeTimes := 1
+	if s, ok := properties[pulsar.SysPropertyReconsumeTimes]; ok {
+		n, _ := strconv.Atoi(s)
+		reconsumeTimes = n + 1
+	} else {
+		properties[pulsar.SysPropertyRealTopic] = msg.Topic()
+		properties[pulsar.SysPropertyOriginMessageID] = msg.ID().String()
+	}
+	properties[pulsar.SysPropertyReconsumeTimes] = strconv.Itoa(reconsumeTimes)
+	properties[pulsar.SysPropertyDelayTime] = strconv.FormatInt(delay.Milliseconds(), 10)
+
+	m := &memoryMessage{
+		producerName: mc.options.Name,
+		properties:   properties,
+		payload:      msg.Payload(),
+		publishTime:  time.Now(),
+		eventTime:    msg.EventTime(),
+		key:          msg.Key(),
+		orderingKey:  msg.OrderingKey(),
+	}
+
+	topic := dlq.RetryLetterTopic
+	if uint32(reconsumeTimes) > dlq.MaxDeliveries {
+		topic = dlq.DeadLetterTopic
+	} else {
+		m.deliverAt = m.publishTime.Add(delay)
+	}
+
+	mc.client.topic(topic).publish(m)
+	_ = mc.Ack(msg)
+}
+
+func (mc *memoryConsumer) Nack(msg pulsar.Message) {
+	mc.NackID(msg.ID())
+}
+
+func (mc *memoryConsumer) NackID(id pulsar.MessageID) {
+	mid, ok := id.(*memoryMessageID)
+	if !ok {
+		return
+	}
+
+	mid.msg.sub.nack(mid.msg, mc.nackDelay(mid.msg))
+}
+
+func (mc *memoryConsumer) Close() {
+	mc.closeOnce.Do(func() {
+		close(mc.closed)
+
+		mc.client.mu.Lock()
+		delete(mc.client.consumers, mc)
+		mc.client.mu.Unlock()
+
+		mc.mu.Lock()
+		subs := slices.Clone(mc.subs)
+		mc.mu.Unlock()
+
+		for _, s := range subs {
+			s.detach(mc)
+		}
+	})
+}
+
+func (mc *memoryConsumer) Seek(pulsar.MessageID) error {
+	return ErrMemorySeekUnsupported
+}
+
+func (mc *memoryConsumer) SeekByTime(time.Time) error {
+	return ErrMemorySeekUnsupported
+}
@@ -0,0 +1,154 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+var ErrMemorySchemaUnsupported = fmt.Errorf("%w: 未设置 Schema", ErrNotSupported)
+
+var (
+	_ pulsar.Message   = (*memoryMessage)(nil)
+	_ pulsar.MessageID = (*memoryMessageID)(nil)
+)
+
+// memoryMessage 内存消息，每个订阅持有独立的副本
+type memoryMessage struct {
+	id              *memoryMessageID
+	topic           string
+	producerName    string
+	properties      map[string]string
+	payload         []byte
+	publishTime     time.Time
+	eventTime       time.Time
+	key             string
+	orderingKey     string
+	deliverAt       time.Time
+	redeliveryCount uint32
+
+	sub    *memorySubscription // 所属订阅
+	schema pulsar.Schema       // 接收消费者的 Schema
+}
+
+// clone 为订阅复制消息
+func (m *memoryMessage) clone(sub *memorySubscription) *memoryMessage {
+	c := *m
+	c.properties = maps.Clone(m.properties)
+	c.sub = sub
+	c.id = &memoryMessageID{entryID: m.id.entryID, ledgerID: m.id.ledgerID, msg: &c}
+	return &c
+}
+
+func (m *memoryMessage) Topic() string {
+	return m.topic
+}
+
+func (m *memoryMessage) ProducerName() string {
+	return m.producerName
+}
+
+func (m *memoryMessage) Properties() map[string]string {
+	return m.properties
+}
+
+func (m *memoryMessage) Payload() []byte {
+	return m.payload
+}
+
+func (m *memoryMessage) ID() pulsar.MessageID {
+	return m.id
+}
+
+func (m *memoryMessage) PublishTime() time.Time {
+	return m.publishTime
+}
+
+func (m *memoryMessage) EventTime() time.Time {
+	return m.eventTime
+}
+
+func (m *memoryMessage) Key() string {
+	return m.key
+}
+
+func (m *memoryMessage) OrderingKey() string {
+	return m.orderingKey
+}
+
+func (m *memoryMessage) RedeliveryCount() uint32 {
+	return m.redeliveryCount
+}
+
+func (m *memoryMessage) IsReplicated() bool {
+	return false
+}
+
+func (m *memoryMessage) GetReplicatedFrom() string {
+	return ""
+}
+
+func (m *memoryMessage) GetSchemaValue(v any) error {
+	if m.schema == nil {
+		return ErrMemorySchemaUnsupported
+	}
+	return m.schema.Decode(m.payload, v)
+}
+
+func (m *memoryMessage) SchemaVersion() []byte {
+	return nil
+}
+
+func (m *memoryMessage) GetEncryptionContext() *pulsar.EncryptionContext {
+	return nil
+}
+
+func (m *memoryMessage) Index() *uint64 {
+	return nil
+}
+
+func (m *memoryMessage) BrokerPublishTime() *time.Time {
+	return nil
+}
+
+// memoryMessageID 内存消息 ID，ledgerID 为 Topic 序号，entryID 为 Topic 内的消息序号
+type memoryMessageID struct {
+	ledgerID int64
+	entryID  int64
+
+	msg *memoryMessage
+}
+
+func (id *memoryMessageID) Serialize() []byte {
+	return []byte(id.String())
+}
+
+func (id *memoryMessageID) LedgerID() int64 {
+	return id.ledgerID
+}
+
+func (id *memoryMessageID) EntryID() int64 {
+	return id.entryID
+}
+
+func (id *memoryMessageID) BatchIdx() int32 {
+	return -1
+}
+
+func (id *memoryMessageID) PartitionIdx() int32 {
+	return -1
+}
+
+func (id *memoryMessageID) BatchSize() int32 {
+	return 0
+}
+
+func (id *memoryMessageID) String() string {
+	return fmt.Sprintf("%d:%d", id.ledgerID, id.entryID)
+}
@@ -0,0 +1,305 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"nexis.run/nexa/kit"
+)
+
+// receiveN 从 consumer 接收 n 条消息并 ack
+func receiveN(t *testing.T, consumer pulsar.Consumer, n int) []pulsar.Message {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	msgs := make([]pulsar.Message, 0, n)
+	for range n {
+		msg, err := consumer.Receive(ctx)
+		require.NoError(t, err)
+		require.NoError(t, consumer.Ack(msg))
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestMemorySubscriptionTypes(t *testing.T) {
+	client := newMemoryClient()
+	defer client.Close()
+
+	subscribe := func(sub string, typ pulsar.SubscriptionType) pulsar.Consumer {
+		consumer, err := client.Subscribe(pulsar.ConsumerOptions{Topic: "orders", SubscriptionName: sub, Type: typ})
+		require.NoError(t, err)
+		return consumer
+	}
+
+	shared := []pulsar.Consumer{subscribe("shared", pulsar.Shared), subscribe("shared", pulsar.Shared)}
+	failover := []pulsar.Consumer{subscribe("failover", pulsar.Failover), subscribe("failover", pulsar.Failover)}
+	keyShared := []pulsar.Consumer{subscribe("key-shared", pulsar.KeyShared), subscribe("key-shared", pulsar.KeyShared)}
+
+	_, err := client.Subscribe(pulsar.ConsumerOptions{Topic: "orders", SubscriptionName: "exclusive", Type: pulsar.Exclusive})
+	require.NoError(t, err)
+	_, err = client.Subscribe(pulsar.ConsumerOptions{Topic: "orders", SubscriptionName: "exclusive", Type: pulsar.Exclusive})
+	require.ErrorIs(t, err, ErrMemoryExclusiveSubscription)
+
+	producer, err := client.CreateProducer(pulsar.ProducerOptions{Topic: "persistent://public/default/orders"})
+	require.NoError(t, err)
+
+	for i := range 10 {
+		_, err = producer.Send(context.Background(), &pulsar.ProducerMessage{
+			Key:     strconv.Itoa(i % 3),
+			Payload: []byte(strconv.Itoa(i)),
+		})
+		require.NoError(t, err)
+	}
+
+	// Shared 轮询分发
+	require.Len(t, receiveN(t, shared[0], 5), 5)
+	require.Len(t, receiveN(t, shared[1], 5), 5)
+
+	// Failover 仅第一个消费者接收，且保持顺序
+	for i, msg := range receiveN(t, failover[0], 10) {
+		require.Equal(t, strconv.Itoa(i), string(msg.Payload()))
+	}
+
+	// KeyShared 同一 key 由同一消费者接收
+	owners := make(map[string]int)
+	var total int
+	for i, consumer := range keyShared {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			msg, err := consumer.Receive(ctx)
+			cancel()
+			if err != nil {
+				break
+			}
+
+			owner, ok := owners[msg.Key()]
+			require.True(t, !ok || owner == i)
+			owners[msg.Key()] = i
+			total++
+		}
+	}
+	require.Equal(t, 10, total)
+}
+
+func TestMemoryNackRedelivery(t *testing.T) {
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	var attempts atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Consume(ctx, "orders", "order-sub", func(msg pulsar.Message) error {
+			require.Equal(t, uint32(attempts.Load()), msg.RedeliveryCount())
+			if attempts.Add(1) < 3 {
+				return errors.New("retry")
+			}
+			cancel()
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest), WithConsumerNackBackoff(10*time.Millisecond, 20*time.Millisecond))
+	}()
+
+	require.NoError(t, bus.Send(context.Background(), "orders", WithPayload([]byte("order"))))
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, int32(3), attempts.Load())
+}
+
+func TestMemoryDeliverAfter(t *testing.T) {
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	received := make(chan string, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bus.Consume(ctx, "orders", "order-sub", func(msg pulsar.Message) error {
+			received <- string(msg.Payload())
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	start := time.Now()
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("later")), WithProducerDeliverAfter(100*time.Millisecond)))
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("now"))))
+
+	require.Equal(t, "now", <-received)
+	require.Equal(t, "later", <-received)
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestMemoryDeadLetter(t *testing.T) {
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Consume(ctx, "orders", "order-sub", func(msg pulsar.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			attempts++
+			if string(msg.Payload()) == "poison" {
+				return errors.New("invalid order")
+			}
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest), WithConsumerMaxRedeliveries(2), WithConsumerNackBackoff(time.Millisecond, time.Millisecond))
+	}()
+
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("poison")), WithProducerKey("k1"), func(message *pulsar.ProducerMessage) {
+		message.OrderingKey = "o1"
+	}))
+
+	// 超过最大投递次数后进入死信 Topic
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts == 2
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	n, err := bus.ReplayDLQ(context.Background(), "orders", "order-sub")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	// 重新投递保留 Key 和 OrderingKey
+	consumer, err := bus.client.Subscribe(pulsar.ConsumerOptions{Topic: "orders", SubscriptionName: "verify", SubscriptionInitialPosition: pulsar.SubscriptionPositionEarliest})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	msg := receiveN(t, consumer, 1)[0]
+	require.Equal(t, "poison", string(msg.Payload()))
+	require.Equal(t, "k1", msg.Key())
+	require.Equal(t, "o1", msg.OrderingKey())
+}
+
+func TestMemoryTrim(t *testing.T) {
+	client := newMemoryClient()
+	defer client.Close()
+
+	producer, err := client.CreateProducer(pulsar.ProducerOptions{Topic: "orders"})
+	require.NoError(t, err)
+
+	send := func(n int) {
+		for range n {
+			_, err = producer.Send(context.Background(), &pulsar.ProducerMessage{Payload: []byte("order")})
+			require.NoError(t, err)
+		}
+	}
+	logLen := func() int {
+		topic := client.topic("orders")
+		topic.mu.Lock()
+		defer topic.mu.Unlock()
+		return len(topic.log)
+	}
+
+	// 没有订阅时保留全部消息
+	send(3)
+	require.Equal(t, 3, logLen())
+
+	fast, err := client.Subscribe(pulsar.ConsumerOptions{Topic: "orders", SubscriptionName: "fast", SubscriptionInitialPosition: pulsar.SubscriptionPositionEarliest})
+	require.NoError(t, err)
+	slow, err := client.Subscribe(pulsar.ConsumerOptions{Topic: "orders", SubscriptionName: "slow", NackRedeliveryDelay: time.Hour})
+	require.NoError(t, err)
+
+	// slow 订阅未确认的消息需要保留
+	send(2)
+	receiveN(t, fast, 5)
+	require.Equal(t, 2, logLen())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	first, err := slow.Receive(ctx)
+	require.NoError(t, err)
+	slow.Nack(first)
+	receiveN(t, slow, 1)
+	require.Equal(t, 2, logLen())
+
+	// 取消订阅后不再保留 slow 订阅等待重投的消息
+	require.NoError(t, slow.Unsubscribe())
+	require.Equal(t, 0, logLen())
+}
+
+func TestMemoryNotSupported(t *testing.T) {
+	client := newMemoryClient()
+	defer client.Close()
+
+	_, err := client.CreateReader(pulsar.ReaderOptions{Topic: "orders"})
+	require.ErrorIs(t, err, ErrNotSupported)
+
+	_, err = client.CreateTableView(pulsar.TableViewOptions{Topic: "orders"})
+	require.ErrorIs(t, err, ErrNotSupported)
+
+	_, err = client.NewTransaction(time.Minute)
+	require.ErrorIs(t, err, ErrNotSupported)
+	require.ErrorIs(t, err, ErrMemoryTransactionUnsupported)
+}
+
+func TestMemoryEnvironment(t *testing.T) {
+	bus := NewMemory(WithEnvironment("nexa", kit.Production))
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	received := make(chan string, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bus.Consume(ctx, "persistent://nexa/production/orders", "order-sub", func(msg pulsar.Message) error {
+			received <- msg.Topic()
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("order"))))
+	require.Equal(t, "persistent://nexa/production/orders", <-received)
+}
+
+func TestMemoryTyped(t *testing.T) {
+	type order struct {
+		ID string `json:"id"`
+	}
+
+	var bus Bus = NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	received := make(chan order, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = Subscribe(ctx, bus, "orders", "order-sub", JSONCodec{}, func(_ pulsar.Message, v order) error {
+			received <- v
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	require.NoError(t, NewTypedProducer[order](bus, "orders", JSONCodec{}).Send(ctx, order{ID: "1"}))
+	require.Equal(t, order{ID: "1"}, <-received)
+}
@@ -0,0 +1,281 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const defaultBacklogInterval = 30 * time.Second
+
+// Metrics 生产和消费指标收集接口
+// topic 标签为 Send / Consume 传入的 Topic，设置 WithEnvironment 时为解析后的完整路径，多 Topic 订阅时为主 Topic
+type Metrics interface {
+	// ObservePublish 记录消息发送耗时和结果
+	ObservePublish(topic string, duration time.Duration, err error)
+	// ObserveHandle 记录消息处理耗时和结果，批量消费时为整批的处理耗时
+	ObserveHandle(topic, subscription string, duration time.Duration, err error)
+	// Ack 记录 ack 消息数量
+	Ack(topic, subscription string, n int)
+	// Nack 记录 nack 或发送到重试 Topic 的消息数量
+	Nack(topic, subscription string, n int)
+	// DeadLetter 记录进入死信 Topic 的消息数量
+	DeadLetter(topic, subscription string, n int)
+	// Backlog 记录订阅积压的消息数量
+	Backlog(topic, subscription string, n int64)
+}
+
+var _ Metrics = nopMetrics{}
+
+// nopMetrics 不收集任何指标
+type nopMetrics struct{}
+
+func (nopMetrics) ObservePublish(string, time.Duration, error)        {}
+func (nopMetrics) ObserveHandle(string, string, time.Duration, error) {}
+func (nopMetrics) Ack(string, string, int)                            {}
+func (nopMetrics) Nack(string, string, int)                           {}
+func (nopMetrics) DeadLetter(string, string, int)                     {}
+func (nopMetrics) Backlog(string, string, int64)                      {}
+
+// WithMetrics 设置指标收集
+// 同时配置 WithAdmin 时，每隔 interval 通过 Admin 查询已创建 consumer 的订阅积压，interval 小于等于 0 时使用默认值 30 秒
+//
+// 使用示例:
+//
+//	metrics, err := NewPrometheusMetrics(prometheus.DefaultRegisterer)
+//	bus, err := New(url, WithAdmin(adminURL), WithMetrics(metrics, time.Minute))
+func WithMetrics(metrics Metrics, interval time.Duration) Option {
+	return func(bus *Pulbus) {
+		if interval <= 0 {
+			interval = defaultBacklogInterval
+		}
+
+		bus.metrics = metrics
+		bus.backlogInterval = interval
+	}
+}
+
+// metricsHook 获取指标收集，未设置时返回不收集指标的实现
+func (bus *Pulbus) metricsHook() Metrics {
+	if bus.metrics == nil {
+		return nopMetrics{}
+	}
+	return bus.metrics
+}
+
+// ReportBacklog 通过 Admin 查询所有已创建 consumer 的订阅积压并记录到指标
+// 多 Topic 订阅分别记录每个 Topic，正则订阅没有确定的 Topic 名称，不记录；
+// 单个订阅查询失败不影响其他订阅，返回所有失败的错误
+func (bus *Pulbus) ReportBacklog(ctx context.Context) error {
+	if bus.admin == nil {
+		return ErrAdminNotConfigured
+	}
+
+	var errs []error
+	bus.consumers.Range(func(key, value any) bool {
+		k := key.(ConsumerKey)
+		spec := value.(*Consumer).spec
+		if spec.topicsPattern {
+			return true
+		}
+
+		topics := []string{k.Topic}
+		if spec.topics != "" {
+			topics = append(topics, strings.Split(spec.topics, ",")...)
+		}
+
+		for _, topic := range topics {
+			backlog, err := bus.subscriptionBacklog(ctx, topic, k.Subscription)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("查询订阅积压失败: topic=%s, subscription=%s: %w", topic, k.Subscription, err))
+				continue
+			}
+
+			bus.metricsHook().Backlog(topic, k.Subscription, backlog)
+		}
+		return true
+	})
+	return errors.Join(errs...)
+}
+
+// subscriptionBacklog 查询订阅积压，分区 Topic 汇总所有分区
+func (bus *Pulbus) subscriptionBacklog(ctx context.Context, topic, subscription string) (int64, error) {
+	name, err := utils.GetTopicName(topic)
+	if err != nil {
+		return 0, err
+	}
+
+	var meta utils.PartitionedTopicMetadata
+	meta, err = bus.admin.Topics().GetMetadataWithContext(ctx, *name)
+	if err != nil {
+		return 0, err
+	}
+
+	if meta.Partitions > 0 {
+		var stats utils.PartitionedTopicStats
+		stats, err = bus.admin.Topics().GetPartitionedStatsWithContext(ctx, *name, false)
+		if err != nil {
+			return 0, err
+		}
+		return stats.Subscriptions[subscription].MsgBacklog, nil
+	}
+
+	var stats utils.TopicStats
+	stats, err = bus.admin.Topics().GetStatsWithContext(ctx, *name)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Subscriptions[subscription].MsgBacklog, nil
+}
+
+// reportBacklogLoop 定时记录订阅积压，Close 时停止
+func (bus *Pulbus) reportBacklogLoop() {
+	ticker := time.NewTicker(bus.backlogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bus.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), bus.backlogInterval)
+			err := bus.ReportBacklog(ctx)
+			cancel()
+			if err != nil {
+				zap.L().Warn("[Pulsar Metrics] 查询订阅积压失败", zap.Error(err))
+			}
+		}
+	}
+}
+
+// willDeadLetter 处理失败的消息重投后是否会进入死信 Topic
+func (o *ConsumerOptions) willDeadLetter(msg pulsar.Message) bool {
+	if !o.deadLetterEnabled() {
+		return false
+	}
+
+	if o.retryLetter {
+		maxDeliveries := o.maxRedeliveries
+		if maxDeliveries == 0 {
+			maxDeliveries = pulsar.MaxReconsumeTimes
+		}
+
+		var reconsumeTimes uint64
+		if s, ok := msg.Properties()[pulsar.SysPropertyReconsumeTimes]; ok {
+			reconsumeTimes, _ = strconv.ParseUint(s, 10, 32)
+		}
+		return reconsumeTimes+1 > uint64(maxDeliveries)
+	}
+
+	return msg.RedeliveryCount()+1 >= o.maxRedeliveries
+}
+
+// resultLabel 返回结果标签
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+var _ Metrics = (*PrometheusMetrics)(nil)
+
+// PrometheusMetrics 基于 Prometheus 的指标收集
+//
+// 指标:
+//   - pulbus_publish_duration_seconds{topic, result} 发送耗时
+//   - pulbus_handle_duration_seconds{topic, subscription, result} 处理耗时
+//   - pulbus_acks_total{topic, subscription} ack 数量
+//   - pulbus_nacks_total{topic, subscription} nack 数量
+//   - pulbus_dead_letters_total{topic, subscription} 死信数量
+//   - pulbus_backlog{topic, subscription} 订阅积压
+type PrometheusMetrics struct {
+	publishDuration *prometheus.HistogramVec
+	handleDuration  *prometheus.HistogramVec
+	acks            *prometheus.CounterVec
+	nacks           *prometheus.CounterVec
+	deadLetters     *prometheus.CounterVec
+	backlog         *prometheus.GaugeVec
+}
+
+// NewPrometheusMetrics 创建 Prometheus 指标收集并注册到 registerer
+func NewPrometheusMetrics(registerer prometheus.Registerer) (*PrometheusMetrics, error) {
+	labels := []string{"topic", "subscription"}
+
+	m := &PrometheusMetrics{
+		publishDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "pulbus",
+			Name:      "publish_duration_seconds",
+			Help:      "Pulsar 消息发送耗时",
+		}, []string{"topic", "result"}),
+		handleDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "pulbus",
+			Name:      "handle_duration_seconds",
+			Help:      "Pulsar 消息处理耗时",
+		}, []string{"topic", "subscription", "result"}),
+		acks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pulbus",
+			Name:      "acks_total",
+			Help:      "Pulsar 消息 ack 数量",
+		}, labels),
+		nacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pulbus",
+			Name:      "nacks_total",
+			Help:      "Pulsar 消息 nack 数量",
+		}, labels),
+		deadLetters: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "pulbus",
+			Name:      "dead_letters_total",
+			Help:      "Pulsar 消息进入死信 Topic 的数量",
+		}, labels),
+		backlog: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "pulbus",
+			Name:      "backlog",
+			Help:      "Pulsar 订阅积压的消息数量",
+		}, labels),
+	}
+
+	for _, c := range []prometheus.Collector{m.publishDuration, m.handleDuration, m.acks, m.nacks, m.deadLetters, m.backlog} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *PrometheusMetrics) ObservePublish(topic string, duration time.Duration, err error) {
+	m.publishDuration.WithLabelValues(topic, resultLabel(err)).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) ObserveHandle(topic, subscription string, duration time.Duration, err error) {
+	m.handleDuration.WithLabelValues(topic, subscription, resultLabel(err)).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) Ack(topic, subscription string, n int) {
+	m.acks.WithLabelValues(topic, subscription).Add(float64(n))
+}
+
+func (m *PrometheusMetrics) Nack(topic, subscription string, n int) {
+	m.nacks.WithLabelValues(topic, subscription).Add(float64(n))
+}
+
+func (m *PrometheusMetrics) DeadLetter(topic, subscription string, n int) {
+	m.deadLetters.WithLabelValues(topic, subscription).Add(float64(n))
+}
+
+func (m *PrometheusMetrics) Backlog(topic, subscription string, n int64) {
+	m.backlog.WithLabelValues(topic, subscription).Set(float64(n))
+}
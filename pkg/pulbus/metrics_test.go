@@ -0,0 +1,115 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func (s stubTopics) GetStatsWithContext(_ context.Context, topic utils.TopicName) (utils.TopicStats, error) {
+	if topic.GetLocalName() == "failing" {
+		return utils.TopicStats{}, errors.New("stats unavailable")
+	}
+	return utils.TopicStats{Subscriptions: map[string]utils.SubscriptionStats{
+		"order-sub": {MsgBacklog: 42},
+	}}, nil
+}
+
+// metricValue 读取 counter 或 gauge 的值
+func metricValue(t *testing.T, metric prometheus.Metric) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, metric.Write(m))
+	if m.Counter != nil {
+		return m.Counter.GetValue()
+	}
+	return m.Gauge.GetValue()
+}
+
+// sampleCount 读取 histogram 的样本数量
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(m))
+	return m.Histogram.GetSampleCount()
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := NewPrometheusMetrics(registry)
+	require.NoError(t, err)
+
+	// 重复注册返回错误
+	_, err = NewPrometheusMetrics(registry)
+	require.Error(t, err)
+
+	bus := NewMemory(WithMetrics(metrics, 0))
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	const topic = "orders"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Consume(ctx, "orders", "order-sub", func(msg pulsar.Message) error {
+			if string(msg.Payload()) == "poison" {
+				return errors.New("invalid order")
+			}
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest), WithConsumerMaxRedeliveries(2), WithConsumerNackBackoff(time.Millisecond, time.Millisecond))
+	}()
+
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("order"))))
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("poison"))))
+
+	require.Eventually(t, func() bool {
+		return metricValue(t, metrics.deadLetters.WithLabelValues(topic, "order-sub")) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	require.Equal(t, uint64(2), sampleCount(t, metrics.publishDuration.WithLabelValues(topic, "success")))
+	require.Equal(t, float64(1), metricValue(t, metrics.acks.WithLabelValues(topic, "order-sub")))
+	require.Equal(t, float64(2), metricValue(t, metrics.nacks.WithLabelValues(topic, "order-sub")))
+	require.Equal(t, uint64(1), sampleCount(t, metrics.handleDuration.WithLabelValues(topic, "order-sub", "success")))
+	require.Equal(t, uint64(2), sampleCount(t, metrics.handleDuration.WithLabelValues(topic, "order-sub", "error")))
+
+	// 通过 Admin 查询订阅积压
+	bus.admin = &Admin{Client: newStubAdmin()}
+	require.NoError(t, bus.ReportBacklog(context.Background()))
+	require.Equal(t, float64(42), metricValue(t, metrics.backlog.WithLabelValues(topic, "order-sub")))
+
+	// 查询失败的订阅不影响其他订阅，正则订阅不查询
+	failing := ConsumerKey{Topic: bus.ResolveTopic("failing"), Subscription: "order-sub"}
+	bus.consumers.Store(failing, &Consumer{key: failing})
+	pattern := ConsumerKey{Topic: "persistent://public/default/orders-.*", Subscription: "order-sub"}
+	bus.consumers.Store(pattern, &Consumer{key: pattern, spec: consumerSpec{topicsPattern: true}})
+	multi := ConsumerKey{Topic: bus.ResolveTopic("payments"), Subscription: "order-sub"}
+	bus.consumers.Store(multi, &Consumer{key: multi, spec: consumerSpec{topics: bus.ResolveTopic("refunds")}})
+
+	for i := 0; i < 5; i++ {
+		err = bus.ReportBacklog(context.Background())
+		require.ErrorContains(t, err, "stats unavailable")
+		require.ErrorContains(t, err, failing.Topic)
+		require.NotContains(t, err.Error(), "orders-.*")
+	}
+	require.Equal(t, float64(42), metricValue(t, metrics.backlog.WithLabelValues(multi.Topic, "order-sub")))
+	require.Equal(t, float64(42), metricValue(t, metrics.backlog.WithLabelValues(bus.ResolveTopic("refunds"), "order-sub")))
+
+	// 移除缓存的 consumer 以免 Close 时关闭
+	for _, key := range []ConsumerKey{failing, pattern, multi} {
+		bus.consumers.Delete(key)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go.uber.org/zap"
+)
+
+// ConsumerMiddleware 消费中间件
+// 与 kit/micro 的 gRPC 中间件一致，先添加的中间件在外层
+type ConsumerMiddleware func(handler ContextHandler) ContextHandler
+
+type consumerContextKey struct{}
+
+// newConsumerContext 将消费者信息写入 context
+func newConsumerContext(ctx context.Context, key ConsumerKey) context.Context {
+	return context.WithValue(ctx, consumerContextKey{}, key)
+}
+
+// FromConsumerContext 从 handler 的 context 中获取消费者的 Topic 和订阅
+func FromConsumerContext(ctx context.Context) (key ConsumerKey, ok bool) {
+	key, ok = ctx.Value(consumerContextKey{}).(ConsumerKey)
+	return
+}
+
+// WithMiddleware 设置所有消费者共用的中间件，在 WithConsumerMiddleware 设置的中间件外层执行
+// RecoverMiddleware 始终在最外层执行，无需手动添加
+//
+// 使用示例:
+//
+//	bus, err := New(url, WithMiddleware(LoggingMiddleware(), TimeoutMiddleware(30*time.Second)))
+func WithMiddleware(middlewares ...ConsumerMiddleware) Option {
+	return func(bus *Pulbus) {
+		bus.middlewares = append(bus.middlewares, middlewares...)
+	}
+}
+
+// WithConsumerMiddleware 设置当前订阅的中间件
+// 注意: 中间件仅作用于 Consume / ConsumeContext / ConsumeWithLoop，不作用于 ConsumeBatch
+func WithConsumerMiddleware(middlewares ...ConsumerMiddleware) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// chain 使用中间件包装 handler，顺序为 RecoverMiddleware -> 总线中间件 -> 订阅中间件
+func (bus *Pulbus) chain(handler ContextHandler, options *ConsumerOptions) ContextHandler {
+	middlewares := make([]ConsumerMiddleware, 0, len(bus.middlewares)+len(options.middlewares)+1)
+	middlewares = append(middlewares, RecoverMiddleware())
+	middlewares = append(middlewares, bus.middlewares...)
+	middlewares = append(middlewares, options.middlewares...)
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// messageFields 消息日志字段
+func messageFields(ctx context.Context, msg pulsar.Message) []zap.Field {
+	fields := []zap.Field{
+		zap.String("topic", msg.Topic()),
+		zap.String("key", msg.Key()),
+		zap.Uint32("redelivery_count", msg.RedeliveryCount()),
+		traceField(ctx),
+	}
+
+	if id := msg.ID(); id != nil {
+		fields = append(fields, zap.String("message_id", id.String()))
+	}
+
+	if key, ok := FromConsumerContext(ctx); ok {
+		fields = append(fields, zap.String("subscription", key.Subscription))
+	}
+
+	return fields
+}
+
+// RecoverMiddleware 捕获 handler 的 panic 并返回错误，消息按处理失败重投
+func RecoverMiddleware() ConsumerMiddleware {
+	return func(handler ContextHandler) ContextHandler {
+		return func(ctx context.Context, msg pulsar.Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					buf := make([]byte, 64<<10) //nolint:mnd
+					n := runtime.Stack(buf, false)
+					buf = buf[:n]
+					err = fmt.Errorf("消息处理崩溃: %v", r)
+					zap.L().Error("[Pulsar Consumer] 捕获消息处理崩溃", append(messageFields(ctx, msg), zap.Error(err), zap.String("stack", string(buf)))...)
+				}
+			}()
+			return handler(ctx, msg)
+		}
+	}
+}
+
+// LoggingMiddleware 记录每条消息的处理结果和耗时
+func LoggingMiddleware() ConsumerMiddleware {
+	return func(handler ContextHandler) ContextHandler {
+		return func(ctx context.Context, msg pulsar.Message) error {
+			startTime := time.Now()
+
+			err := handler(ctx, msg)
+
+			fields := append(messageFields(ctx, msg), zap.Duration("duration", time.Since(startTime)))
+			if err != nil {
+				zap.L().Error("[Pulsar Consumer] 消息处理失败", append(fields, zap.Error(err))...)
+			} else {
+				zap.L().Info("[Pulsar Consumer] 消息处理完成", fields...)
+			}
+
+			return err
+		}
+	}
+}
+
+// TimeoutMiddleware 为 handler 的 context 设置超时时间
+// handler 需要使用 context 才能在超时后中止，超时返回的错误按处理失败重投
+func TimeoutMiddleware(timeout time.Duration) ConsumerMiddleware {
+	return func(handler ContextHandler) ContextHandler {
+		return func(ctx context.Context, msg pulsar.Message) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return handler(ctx, msg)
+		}
+	}
+}
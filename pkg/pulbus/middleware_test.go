@@ -0,0 +1,81 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recordMiddleware 记录中间件执行顺序
+func recordMiddleware(name string, calls *[]string) ConsumerMiddleware {
+	return func(handler ContextHandler) ContextHandler {
+		return func(ctx context.Context, msg pulsar.Message) error {
+			*calls = append(*calls, name)
+			return handler(ctx, msg)
+		}
+	}
+}
+
+func TestConsumerMiddleware(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	var calls []string
+	bus := NewMemory(WithMiddleware(recordMiddleware("bus", &calls), LoggingMiddleware()))
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	type result struct {
+		key      ConsumerKey
+		deadline bool
+	}
+	ch := make(chan result, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.ConsumeContext(ctx, "orders", "order-sub", func(ctx context.Context, msg pulsar.Message) error {
+			// panic 被捕获，消息重投
+			if msg.RedeliveryCount() == 0 {
+				panic("boom")
+			}
+
+			key, _ := FromConsumerContext(ctx)
+			_, deadline := ctx.Deadline()
+			ch <- result{key: key, deadline: deadline}
+			return nil
+		},
+			WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest),
+			WithConsumerNackBackoff(time.Millisecond, time.Millisecond),
+			WithConsumerMiddleware(recordMiddleware("subscription", &calls), TimeoutMiddleware(time.Second)),
+		)
+	}()
+
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("order"))))
+
+	r := <-ch
+	require.Equal(t, ConsumerKey{Topic: "orders", Subscription: "order-sub"}, r.key)
+	require.True(t, r.deadline)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	require.Equal(t, []string{"bus", "subscription", "bus", "subscription"}, calls)
+	require.Len(t, logs.FilterMessage("[Pulsar Consumer] 捕获消息处理崩溃").All(), 1)
+
+	failed := logs.FilterMessage("[Pulsar Consumer] 消息处理失败，Nack 消息").All()
+	require.Len(t, failed, 1)
+	require.Equal(t, "order-sub", failed[0].ContextMap()["subscription"])
+	require.Len(t, logs.FilterMessage("[Pulsar Consumer] 消息处理完成").All(), 1)
+}
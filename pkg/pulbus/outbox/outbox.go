@@ -0,0 +1,374 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+// Package outbox 基于事务发件箱的可靠消息发送
+//
+// 消息与业务数据在同一事务中写入发件箱表 (entx.OutboxMixin)，事务提交后由 Relay 在后台按 ID 顺序发送并标记为已发送，
+// 避免写库后、发送前进程退出导致消息丢失。消息至少发送一次，消费者可使用 DedupeKey 配合 pulbus.IdempotencyMiddleware 去重。
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go.uber.org/zap"
+
+	"nexis.run/nexa/pkg/pulbus"
+)
+
+// PropertyOutboxID 发件箱消息 ID 属性名
+const PropertyOutboxID = "outbox-id"
+
+const (
+	// DefaultInterval 默认轮询间隔
+	DefaultInterval = time.Second
+
+	// DefaultBatchSize 默认每批读取的消息数量
+	DefaultBatchSize = 100
+
+	// DefaultLeaseKey 默认租约 key
+	DefaultLeaseKey = "pulbus-outbox"
+
+	// DefaultLeaseTTL 默认租约时长，需大于发送一批消息的耗时
+	DefaultLeaseTTL = 30 * time.Second
+
+	// DefaultRetention 默认已发送消息的保留时间
+	DefaultRetention = 7 * 24 * time.Hour
+
+	// DefaultCleanupInterval 默认清理已发送消息的间隔
+	DefaultCleanupInterval = time.Hour
+)
+
+// RetryPolicy 发送失败重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大发送次数，超过后放弃发送，0 表示不限制
+	MinBackoff  time.Duration // 首次重试延迟
+	MaxBackoff  time.Duration // 最大重试延迟
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 10,
+	MinBackoff:  time.Second,
+	MaxBackoff:  5 * time.Minute,
+}
+
+// backoff 返回第 attempts 次失败后的重试延迟
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempts && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Message 发件箱消息
+type Message struct {
+	ID          int64
+	Topic       string
+	Key         string
+	Payload     []byte
+	Properties  map[string]string
+	Attempts    int       // 已失败次数
+	AvailableAt time.Time // 可发送时间
+}
+
+// Store 发件箱存储
+type Store interface {
+	// Pending 按 ID 顺序获取 ID 大于 after 且未发送、未放弃的消息，包含尚未到重试时间的消息
+	Pending(ctx context.Context, after int64, limit int) ([]*Message, error)
+
+	// MarkSent 标记消息已发送
+	MarkSent(ctx context.Context, ids ...int64) error
+
+	// MarkRetry 记录发送失败并在 availableAt 后重试
+	MarkRetry(ctx context.Context, id int64, availableAt time.Time, cause error) error
+
+	// MarkFailed 记录发送失败并放弃发送
+	MarkFailed(ctx context.Context, id int64, cause error) error
+
+	// Purge 删除 before 之前发送的消息，返回删除数量
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// DedupeKey 使用发件箱消息 ID 作为去重 key，配合 pulbus.WithDedupeKey 使用
+func DedupeKey(msg pulsar.Message) string {
+	id := msg.Properties()[PropertyOutboxID]
+	if id == "" {
+		return ""
+	}
+	return PropertyOutboxID + ":" + id
+}
+
+// Relay 发件箱中继，将发件箱中的消息发送到 Pulsar
+//
+// 多实例部署时通过租约保证同一时间只有一个实例发送，租约存储需使用共享存储，例如 pulbus.NewSQLDedupeStore。
+// 相同 Key 的消息严格按写入顺序发送，某条消息发送失败时，同 Key 的后续消息等待其重试成功或放弃后再发送；
+// 未设置 Key 的消息之间不保证失败重试后的顺序。
+type Relay struct {
+	bus   pulbus.Bus
+	store Store
+	lease pulbus.DedupeStore
+
+	interval        time.Duration
+	batchSize       int
+	leaseKey        string
+	leaseTTL        time.Duration
+	retry           RetryPolicy
+	retention       time.Duration
+	cleanupInterval time.Duration
+
+	notify      chan struct{}
+	lastCleanup time.Time
+}
+
+// Option 发件箱中继配置选项
+type Option func(*Relay)
+
+// WithLease 设置租约存储和租约 key，默认使用内存存储，仅适用于单实例部署
+func WithLease(store pulbus.DedupeStore, key string) Option {
+	return func(r *Relay) {
+		r.lease = store
+		r.leaseKey = key
+	}
+}
+
+// WithLeaseTTL 设置租约时长，默认 30s，需大于发送一批消息的耗时
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(r *Relay) {
+		r.leaseTTL = ttl
+	}
+}
+
+// WithInterval 设置轮询间隔，默认 1s
+func WithInterval(interval time.Duration) Option {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithBatchSize 设置每批读取的消息数量，默认 100
+func WithBatchSize(size int) Option {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithRetryPolicy 设置发送失败重试策略，默认 DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(r *Relay) {
+		r.retry = policy
+	}
+}
+
+// WithRetention 设置已发送消息的保留时间和清理间隔，默认保留 7 天、每小时清理，retention 为 0 时不清理
+func WithRetention(retention, interval time.Duration) Option {
+	return func(r *Relay) {
+		r.retention = retention
+		r.cleanupInterval = interval
+	}
+}
+
+// New 创建发件箱中继
+//
+// 使用示例:
+//
+//	relay := outbox.New(bus, outbox.NewSQLStore(drv), outbox.WithLease(pulbus.NewSQLDedupeStore(drv), "order-outbox"))
+//	go relay.Run(ctx)
+//
+//	// 事务提交后立即触发发送，无需等待下次轮询
+//	err = tx.Commit()
+//	relay.Notify()
+func New(bus pulbus.Bus, store Store, opts ...Option) *Relay {
+	r := &Relay{
+		bus:             bus,
+		store:           store,
+		interval:        DefaultInterval,
+		batchSize:       DefaultBatchSize,
+		leaseKey:        DefaultLeaseKey,
+		leaseTTL:        DefaultLeaseTTL,
+		retry:           DefaultRetryPolicy,
+		retention:       DefaultRetention,
+		cleanupInterval: DefaultCleanupInterval,
+		notify:          make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.lease == nil {
+		r.lease = pulbus.NewMemoryDedupeStore()
+	}
+
+	return r
+}
+
+// Notify 触发一次发送，不会阻塞
+func (r *Relay) Notify() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run 轮询并发送发件箱中的消息，阻塞直到 context 取消
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			zap.L().Error("[Outbox] 发送消息失败", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-ticker.C:
+		case <-r.notify:
+		}
+	}
+}
+
+// Flush 获取租约后发送所有可发送的消息，并按清理间隔删除过期的已发送消息
+// 未获取到租约时直接返回
+func (r *Relay) Flush(ctx context.Context) error {
+	acquired, err := r.lease.Acquire(ctx, r.leaseKey, r.leaseTTL)
+	if err != nil || !acquired {
+		return err
+	}
+	defer func() {
+		if releaseErr := r.lease.Release(context.WithoutCancel(ctx), r.leaseKey); releaseErr != nil {
+			zap.L().Error("[Outbox] 释放租约失败", zap.String("key", r.leaseKey), zap.Error(releaseErr))
+		}
+	}()
+
+	// 租约到期前停止发送，避免与其他实例同时发送
+	ctx, cancel := context.WithTimeout(ctx, r.leaseTTL)
+	defer cancel()
+
+	var (
+		after   int64
+		blocked = make(map[string]bool)
+	)
+	for {
+		messages, err := r.store.Pending(ctx, after, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		err = r.publish(ctx, messages, blocked)
+		if err != nil || ctx.Err() != nil {
+			return err
+		}
+
+		if len(messages) < r.batchSize {
+			break
+		}
+		after = messages[len(messages)-1].ID
+	}
+
+	return r.cleanup(ctx)
+}
+
+// publish 按顺序发送一批消息，blocked 记录存在等待重试消息的 Key，同 Key 的后续消息跳过
+func (r *Relay) publish(ctx context.Context, messages []*Message, blocked map[string]bool) (err error) {
+	var (
+		now  = time.Now()
+		sent = make([]int64, 0, len(messages))
+	)
+
+	defer func() {
+		// 已发送的消息必须标记，context 取消时也需要写入
+		if markErr := r.store.MarkSent(context.WithoutCancel(ctx), sent...); markErr != nil {
+			err = errors.Join(err, markErr)
+		}
+	}()
+
+	for _, m := range messages {
+		if m.Key != "" && blocked[m.Key] {
+			continue
+		}
+
+		if m.AvailableAt.After(now) {
+			blocked[m.Key] = true
+			continue
+		}
+
+		if sendErr := r.send(ctx, m); sendErr != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			retrying, failErr := r.fail(ctx, m, sendErr)
+			if failErr != nil {
+				return failErr
+			}
+			blocked[m.Key] = retrying
+			continue
+		}
+
+		sent = append(sent, m.ID)
+	}
+
+	return nil
+}
+
+// send 发送单条消息
+func (r *Relay) send(ctx context.Context, m *Message) error {
+	opts := []pulbus.ProducerOption{
+		pulbus.WithPayload(m.Payload),
+		pulbus.WithProperties(m.Properties),
+		pulbus.WithProperties(map[string]string{PropertyOutboxID: strconv.FormatInt(m.ID, 10)}),
+	}
+	if m.Key != "" {
+		opts = append(opts, pulbus.WithProducerKey(m.Key))
+	}
+	return r.bus.Send(ctx, m.Topic, opts...)
+}
+
+// fail 记录发送失败，超过最大发送次数后放弃发送，返回是否等待重试
+func (r *Relay) fail(ctx context.Context, m *Message, cause error) (bool, error) {
+	attempts := m.Attempts + 1
+	fields := []zap.Field{
+		zap.Int64("id", m.ID),
+		zap.String("topic", m.Topic),
+		zap.String("key", m.Key),
+		zap.Int("attempts", attempts),
+		zap.Error(cause),
+	}
+
+	if r.retry.MaxAttempts > 0 && attempts >= r.retry.MaxAttempts {
+		zap.L().Error("[Outbox] 超过最大发送次数，放弃发送", fields...)
+		return false, r.store.MarkFailed(ctx, m.ID, cause)
+	}
+
+	zap.L().Warn("[Outbox] 发送失败，等待重试", fields...)
+	return true, r.store.MarkRetry(ctx, m.ID, time.Now().Add(r.retry.backoff(attempts)), cause)
+}
+
+// cleanup 删除过期的已发送消息
+func (r *Relay) cleanup(ctx context.Context) error {
+	if r.retention <= 0 || time.Since(r.lastCleanup) < r.cleanupInterval {
+		return nil
+	}
+
+	n, err := r.store.Purge(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		return err
+	}
+
+	r.lastCleanup = time.Now()
+	if n > 0 {
+		zap.L().Info("[Outbox] 已清理过期消息", zap.Int64("count", n))
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package outbox
+
+import (
+	"context"
+	stdsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"nexis.run/nexa/pkg/pulbus"
+)
+
+// memoryStore 仅用于测试的内存发件箱
+type memoryStore struct {
+	mu       sync.Mutex
+	messages []*Message
+	sent     map[int64]time.Time
+	failed   map[int64]error
+}
+
+func newMemoryStore(messages ...*Message) *memoryStore {
+	for i, m := range messages {
+		m.ID = int64(i + 1)
+	}
+	return &memoryStore{messages: messages, sent: make(map[int64]time.Time), failed: make(map[int64]error)}
+}
+
+func (s *memoryStore) Pending(_ context.Context, after int64, limit int) (messages []*Message, _ error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range s.messages {
+		if _, ok := s.sent[m.ID]; ok || s.failed[m.ID] != nil || m.ID <= after {
+			continue
+		}
+		if len(messages) == limit {
+			break
+		}
+		copied := *m
+		messages = append(messages, &copied)
+	}
+	return
+}
+
+func (s *memoryStore) MarkSent(_ context.Context, ids ...int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		s.sent[id] = time.Now()
+	}
+	return nil
+}
+
+func (s *memoryStore) MarkRetry(_ context.Context, id int64, availableAt time.Time, _ error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.messages[id-1]
+	m.Attempts++
+	m.AvailableAt = availableAt
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(_ context.Context, id int64, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages[id-1].Attempts++
+	s.failed[id] = cause
+	return nil
+}
+
+func (s *memoryStore) Purge(_ context.Context, before time.Time) (n int64, _ error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, at := range s.sent {
+		if at.Before(before) {
+			delete(s.sent, id)
+			n++
+		}
+	}
+	return
+}
+
+func TestRelay(t *testing.T) {
+	bus := pulbus.NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	// 空消息内容发送失败，同 Key 的后续消息等待重试
+	store := newMemoryStore(
+		&Message{Topic: "orders", Key: "a", Payload: []byte("a1"), Properties: map[string]string{"source": "outbox"}},
+		&Message{Topic: "orders", Key: "b"},
+		&Message{Topic: "orders", Key: "b", Payload: []byte("b2")},
+		&Message{Topic: "orders", Key: "a", Payload: []byte("a2")},
+		&Message{Topic: "orders", Payload: []byte("c1")},
+	)
+	relay := New(bus, store,
+		WithBatchSize(2),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, MinBackoff: 50 * time.Millisecond}),
+		WithRetention(0, 0),
+	)
+
+	received := make(chan pulsar.Message, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bus.Consume(ctx, "orders", "order-sub", func(msg pulsar.Message) error {
+			received <- msg
+			return nil
+		}, pulbus.WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	require.NoError(t, relay.Flush(ctx))
+	require.Len(t, store.sent, 3)
+	require.Equal(t, 1, store.messages[1].Attempts)
+
+	// 等待重试期间同 Key 的消息不发送
+	require.NoError(t, relay.Flush(ctx))
+	require.NotContains(t, store.sent, int64(3))
+
+	// 超过最大发送次数后放弃发送，同 Key 的后续消息继续发送
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, relay.Flush(ctx))
+	require.ErrorIs(t, store.failed[2], pulbus.ErrEmptyPayload)
+	require.Contains(t, store.sent, int64(3))
+
+	var payloads []string
+	for range 4 {
+		msg := <-received
+		payloads = append(payloads, string(msg.Payload()))
+		if msg.Key() == "a" {
+			require.NotEmpty(t, DedupeKey(msg))
+		}
+	}
+	require.Equal(t, []string{"a1", "a2", "c1", "b2"}, payloads)
+}
+
+func TestRelayLease(t *testing.T) {
+	bus := pulbus.NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	ctx := context.Background()
+	lease := pulbus.NewMemoryDedupeStore()
+	store := newMemoryStore(&Message{Topic: "orders", Payload: []byte("order")})
+
+	// 其他实例持有租约时不发送
+	ok, err := lease.Acquire(ctx, DefaultLeaseKey, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	relay := New(bus, store, WithLease(lease, DefaultLeaseKey))
+	require.NoError(t, relay.Flush(ctx))
+	require.Empty(t, store.sent)
+
+	require.NoError(t, lease.Release(ctx, DefaultLeaseKey))
+	require.NoError(t, relay.Flush(ctx))
+	require.Len(t, store.sent, 1)
+	require.Zero(t, lease.Len())
+
+	// 清理过期的已发送消息
+	relay.retention, relay.cleanupInterval = time.Nanosecond, 0
+	require.NoError(t, relay.Flush(ctx))
+	require.Empty(t, store.sent)
+}
+
+func TestRelayRun(t *testing.T) {
+	bus := pulbus.NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	store := newMemoryStore(&Message{Topic: "orders", Payload: []byte("order")})
+	relay := New(bus, store, WithInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- relay.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.sent) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// Notify 立即触发发送
+	store.mu.Lock()
+	store.messages = append(store.messages, &Message{ID: 2, Topic: "orders", Payload: []byte("order")})
+	store.mu.Unlock()
+	relay.Notify()
+
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.sent) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	require.Equal(t, time.Second, policy.backoff(1))
+	require.Equal(t, 2*time.Second, policy.backoff(2))
+	require.Equal(t, 4*time.Second, policy.backoff(3))
+	require.Equal(t, 5*time.Second, policy.backoff(4))
+}
+
+// stubDriver 记录执行语句的 ent 驱动
+type stubDriver struct {
+	dialect.Driver
+
+	queries []string
+	rows    [][]driver.Value
+}
+
+func (d *stubDriver) Dialect() string {
+	return dialect.Postgres
+}
+
+func (d *stubDriver) Exec(_ context.Context, query string, _, v any) error {
+	d.queries = append(d.queries, query)
+	*v.(*stdsql.Result) = driver.RowsAffected(1)
+	return nil
+}
+
+func (d *stubDriver) Query(_ context.Context, query string, _, v any) error {
+	d.queries = append(d.queries, query)
+	*v.(*sql.Rows) = sql.Rows{ColumnScanner: &stubRows{rows: d.rows}}
+	return nil
+}
+
+// stubRows 按顺序返回预设数据的结果集
+type stubRows struct {
+	sql.ColumnScanner
+
+	rows [][]driver.Value
+	cur  []driver.Value
+}
+
+func (r *stubRows) Next() bool {
+	if len(r.rows) == 0 {
+		r.cur = nil
+		return false
+	}
+	r.cur, r.rows = r.rows[0], r.rows[1:]
+	return true
+}
+
+func (r *stubRows) Scan(dest ...any) error {
+	if r.cur == nil {
+		return io.EOF
+	}
+	for i, v := range r.cur {
+		if scanner, ok := dest[i].(stdsql.Scanner); ok {
+			if err := scanner.Scan(v); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch d := dest[i].(type) {
+		case *int64:
+			*d = v.(int64)
+		case *int:
+			*d = int(v.(int64))
+		case *string:
+			*d = v.(string)
+		case *[]byte:
+			if v != nil {
+				*d = v.([]byte)
+			}
+		case *time.Time:
+			*d = v.(time.Time)
+		default:
+			return errors.New("unsupported scan type")
+		}
+	}
+	return nil
+}
+
+func (r *stubRows) Err() error {
+	return nil
+}
+
+func (r *stubRows) Close() error {
+	return nil
+}
+
+func TestSQLStore(t *testing.T) {
+	now := time.Now()
+	drv := &stubDriver{rows: [][]driver.Value{
+		{int64(1), "orders", "a", []byte("a1"), []byte(`{"source":"outbox"}`), int64(0), now},
+		{int64(2), "orders", nil, []byte("c1"), nil, int64(1), now},
+	}}
+	store := NewSQLStore(drv, WithTable("order_outboxes"))
+	ctx := context.Background()
+
+	messages, err := store.Pending(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []*Message{
+		{ID: 1, Topic: "orders", Key: "a", Payload: []byte("a1"), Properties: map[string]string{"source": "outbox"}, AvailableAt: now},
+		{ID: 2, Topic: "orders", Payload: []byte("c1"), Attempts: 1, AvailableAt: now},
+	}, messages)
+
+	require.NoError(t, store.MarkSent(ctx))
+	require.NoError(t, store.MarkSent(ctx, 1, 2))
+	require.NoError(t, store.MarkRetry(ctx, 1, now, errors.New("boom")))
+	require.NoError(t, store.MarkFailed(ctx, 1, errors.New("boom")))
+
+	n, err := store.Purge(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	require.Len(t, drv.queries, 5)
+	require.True(t, strings.HasPrefix(drv.queries[0], `SELECT "id", "topic", "message_key", "payload", "properties", "attempts", "available_at" FROM "order_outboxes" WHERE "id" > $1 AND "sent_at" IS NULL AND "failed_at" IS NULL ORDER BY "id" LIMIT 10`))
+	require.Equal(t, `UPDATE "order_outboxes" SET "sent_at" = $1 WHERE "id" IN ($2, $3)`, drv.queries[1])
+	require.Equal(t, `UPDATE "order_outboxes" SET "attempts" = COALESCE("order_outboxes"."attempts", 0) + $1, "last_error" = $2, "available_at" = $3 WHERE "id" IN ($4)`, drv.queries[2])
+	require.Equal(t, `DELETE FROM "order_outboxes" WHERE "sent_at" < $1`, drv.queries[4])
+}
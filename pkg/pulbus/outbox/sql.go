@@ -0,0 +1,161 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package outbox
+
+import (
+	"context"
+	stdsql "database/sql"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/bytedance/sonic"
+
+	"nexis.run/nexa/kit/entx"
+)
+
+// DefaultTable 默认发件箱表名，即 ent 中名为 Outbox 的 schema 生成的表名
+const DefaultTable = "outboxes"
+
+// SQLStore 基于 ent SQL 驱动的发件箱存储，表结构由 entx.OutboxMixin 定义
+type SQLStore struct {
+	driver dialect.Driver
+	table  string
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// SQLOption SQL 发件箱存储选项
+type SQLOption func(*SQLStore)
+
+// WithTable 设置发件箱表名，默认 outboxes
+func WithTable(table string) SQLOption {
+	return func(s *SQLStore) {
+		s.table = table
+	}
+}
+
+// NewSQLStore 创建 SQL 发件箱存储，driver 可使用 ent 客户端的驱动或 entsql.OpenDB 创建
+//
+// 使用示例:
+//
+//	store := outbox.NewSQLStore(entsql.OpenDB(dialect.Postgres, db))
+func NewSQLStore(driver dialect.Driver, opts ...SQLOption) *SQLStore {
+	s := &SQLStore{
+		driver: driver,
+		table:  DefaultTable,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// update 执行更新语句
+func (s *SQLStore) update(ctx context.Context, set func(*sql.UpdateBuilder) *sql.UpdateBuilder, ids ...int64) error {
+	values := make([]any, len(ids))
+	for i, id := range ids {
+		values[i] = id
+	}
+
+	builder := sql.Dialect(s.driver.Dialect()).Update(s.table)
+	query, args := set(builder).Where(sql.In("id", values...)).Query()
+
+	var res stdsql.Result
+	return s.driver.Exec(ctx, query, args, &res)
+}
+
+// Pending 按 ID 顺序获取 ID 大于 after 且未发送、未放弃的消息
+func (s *SQLStore) Pending(ctx context.Context, after int64, limit int) ([]*Message, error) {
+	query, args := sql.Dialect(s.driver.Dialect()).
+		Select(
+			"id",
+			entx.OutboxFieldTopic,
+			entx.OutboxFieldKey,
+			entx.OutboxFieldPayload,
+			entx.OutboxFieldProperties,
+			entx.OutboxFieldAttempts,
+			entx.OutboxFieldAvailableAt,
+		).
+		From(sql.Table(s.table)).
+		Where(sql.And(
+			sql.GT("id", after),
+			sql.IsNull(entx.OutboxFieldSentAt),
+			sql.IsNull(entx.OutboxFieldFailedAt),
+		)).
+		OrderBy("id").
+		Limit(limit).
+		Query()
+
+	var rows sql.Rows
+	if err := s.driver.Query(ctx, query, args, &rows); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var messages []*Message
+	for rows.Next() {
+		var (
+			m          Message
+			key        stdsql.NullString
+			properties []byte
+		)
+		if err := rows.Scan(&m.ID, &m.Topic, &key, &m.Payload, &properties, &m.Attempts, &m.AvailableAt); err != nil {
+			return nil, err
+		}
+
+		m.Key = key.String
+		if len(properties) > 0 {
+			if err := sonic.Unmarshal(properties, &m.Properties); err != nil {
+				return nil, err
+			}
+		}
+		messages = append(messages, &m)
+	}
+	return messages, rows.Err()
+}
+
+// MarkSent 标记消息已发送
+func (s *SQLStore) MarkSent(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.update(ctx, func(b *sql.UpdateBuilder) *sql.UpdateBuilder {
+		return b.Set(entx.OutboxFieldSentAt, time.Now())
+	}, ids...)
+}
+
+// MarkRetry 记录发送失败并在 availableAt 后重试
+func (s *SQLStore) MarkRetry(ctx context.Context, id int64, availableAt time.Time, cause error) error {
+	return s.update(ctx, func(b *sql.UpdateBuilder) *sql.UpdateBuilder {
+		return b.Add(entx.OutboxFieldAttempts, 1).
+			Set(entx.OutboxFieldLastError, cause.Error()).
+			Set(entx.OutboxFieldAvailableAt, availableAt)
+	}, id)
+}
+
+// MarkFailed 记录发送失败并放弃发送
+func (s *SQLStore) MarkFailed(ctx context.Context, id int64, cause error) error {
+	return s.update(ctx, func(b *sql.UpdateBuilder) *sql.UpdateBuilder {
+		return b.Add(entx.OutboxFieldAttempts, 1).
+			Set(entx.OutboxFieldLastError, cause.Error()).
+			Set(entx.OutboxFieldFailedAt, time.Now())
+	}, id)
+}
+
+// Purge 删除 before 之前发送的消息，返回删除数量
+func (s *SQLStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query, args := sql.Dialect(s.driver.Dialect()).Delete(s.table).
+		Where(sql.LT(entx.OutboxFieldSentAt, before)).
+		Query()
+
+	var res stdsql.Result
+	if err := s.driver.Exec(ctx, query, args, &res); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -7,12 +7,15 @@ package pulbus
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
-	"github.com/bytedance/sonic"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
+)
+
+var (
+	ErrEmptyPayload            = errors.New("消息内容不能为空")
+	ErrProducerOptionsConflict = errors.New("producer 已使用不同的配置创建")
 )
 
 type ProducerOption func(*pulsar.ProducerMessage)
@@ -65,26 +68,161 @@ func WithSequenceID(seqID int64) ProducerOption {
 	}
 }
 
+// WithProperties 设置消息属性，多次调用时合并
+func WithProperties(properties map[string]string) ProducerOption {
+	return func(message *pulsar.ProducerMessage) {
+		if message.Properties == nil {
+			message.Properties = make(map[string]string, len(properties))
+		}
+		for k, v := range properties {
+			message.Properties[k] = v
+		}
+	}
+}
+
+// WithEventTime 设置消息的业务事件时间
+func WithEventTime(t time.Time) ProducerOption {
+	return func(message *pulsar.ProducerMessage) {
+		message.EventTime = t
+	}
+}
+
+// WithDeliverAt 设置消息在指定时间投递
+// 注意: 延迟投递仅对 Shared 和 KeyShared 订阅生效
+func WithDeliverAt(t time.Time) ProducerOption {
+	return func(message *pulsar.ProducerMessage) {
+		message.DeliverAt = t
+	}
+}
+
+// withProperty 设置单个消息属性
+func withProperty(key, value string) ProducerOption {
+	return func(message *pulsar.ProducerMessage) {
+		if message.Properties == nil {
+			message.Properties = make(map[string]string)
+		}
+		message.Properties[key] = value
+	}
+}
+
+// ProducerConfig Producer 配置，同一 Topic 的 producer 只会按首次使用的配置创建一次
+type ProducerConfig struct {
+	DisableBatching         bool                      // 禁用批量发送
+	BatchingMaxMessages     uint                      // 每批最大消息数量，默认 1000
+	BatchingMaxPublishDelay time.Duration             // 批量发送最大延迟，默认 10ms
+	CompressionType         pulsar.CompressionType    // 压缩类型，默认不压缩
+	MaxPendingMessages      int                       // 等待 broker 确认的最大消息数量
+	SendTimeout             time.Duration             // 发送超时时间，默认 30s
+	AccessMode              pulsar.ProducerAccessMode // 访问模式，默认 Shared
+	Schema                  pulsar.Schema             // 消息 Schema，设置后使用 ProducerMessage.Value 发送
+}
+
+// options 转换为 pulsar producer 配置
+func (cfg ProducerConfig) options(topic string) pulsar.ProducerOptions {
+	return pulsar.ProducerOptions{
+		Topic:                   topic,
+		DisableBatching:         cfg.DisableBatching,
+		BatchingMaxMessages:     cfg.BatchingMaxMessages,
+		BatchingMaxPublishDelay: cfg.BatchingMaxPublishDelay,
+		CompressionType:         cfg.CompressionType,
+		MaxPendingMessages:      cfg.MaxPendingMessages,
+		SendTimeout:             cfg.SendTimeout,
+		ProducerAccessMode:      cfg.AccessMode,
+		Schema:                  cfg.Schema,
+	}
+}
+
+// equal 判断配置是否相同，Schema 按类型和定义比较
+func (cfg ProducerConfig) equal(other ProducerConfig) bool {
+	a, b := cfg.Schema, other.Schema
+	cfg.Schema, other.Schema = nil, nil
+	return cfg == other && schemaEqual(a, b)
+}
+
+// schemaEqual 按类型和定义判断 Schema 是否相同，不同实例的相同 Schema 视为相同
+func schemaEqual(a, b pulsar.Schema) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	ai, bi := a.GetSchemaInfo(), b.GetSchemaInfo()
+	if ai == nil || bi == nil {
+		return ai == nil && bi == nil
+	}
+	return ai.Type == bi.Type && ai.Schema == bi.Schema
+}
+
+// WithProducerConfig 配置指定 Topic 的 producer
+//
+// 使用示例:
+//
+//	bus, err := New(url, WithProducerConfig("events", ProducerConfig{
+//	    BatchingMaxMessages:     500,
+//	    BatchingMaxPublishDelay: 50 * time.Millisecond,
+//	    CompressionType:         pulsar.LZ4,
+//	}))
+func WithProducerConfig(topic string, cfg ProducerConfig) Option {
+	return func(bus *Pulbus) {
+		bus.producerConfigs.Store(topic, cfg)
+	}
+}
+
 type Producer struct {
+	config ProducerConfig
+
 	pulsar.Producer
 }
 
-// 生产日志记录
-func (producer *Producer) log(level zapcore.Level, message string, data pulsar.Message) {
-	b, _ := sonic.Marshal(data)
-	zap.L().Log(level, "[Pulsar Producer] "+message, zap.ByteString("message", b), zap.String("topic", producer.Topic()))
+// ConfigureProducer 配置指定 Topic 的 producer
+// 若该 Topic 的 producer 已使用不同的配置创建，返回 ErrProducerOptionsConflict
+func (bus *Pulbus) ConfigureProducer(topic string, cfg ProducerConfig) error {
+	topic = bus.ResolveTopic(topic)
+
+	unlock := bus.producerLocks.lock(topic)
+	defer unlock()
+
+	if p, ok := bus.producers.Load(topic); ok && !p.(*Producer).config.equal(cfg) {
+		return fmt.Errorf("%w: topic=%s", ErrProducerOptionsConflict, topic)
+	}
+
+	bus.producerConfigs.Store(topic, cfg)
+	return nil
 }
 
-// getProducer 获取 Producer
-func (bus *Pulbus) getProducer(topic string, opts pulsar.ProducerOptions) (pulsar.Producer, error) {
+// getProducer 获取 Producer，使用 ConfigureProducer 或 WithProducerConfig 设置的配置创建
+// 同一 Topic 并发首次发送时只创建一个 producer
+func (bus *Pulbus) getProducer(topic string) (*Producer, error) {
+	name := topic
+	topic = bus.ResolveTopic(topic)
+
 	// 尝试从缓存中获取
 	if p, ok := bus.producers.Load(topic); ok {
-		return p.(pulsar.Producer), nil
+		return p.(*Producer), nil
+	}
+
+	unlock := bus.producerLocks.lock(topic)
+	defer unlock()
+
+	// 加锁后再次检查，其他调用方可能已创建
+	if p, ok := bus.producers.Load(topic); ok {
+		return p.(*Producer), nil
+	}
+
+	// WithProducerConfig 可能在 WithEnvironment 之前应用，同时查找原始名称
+	var cfg ProducerConfig
+	if c, ok := bus.producerConfigs.Load(topic); ok {
+		cfg = c.(ProducerConfig)
+	} else if c, ok = bus.producerConfigs.Load(name); ok {
+		cfg = c.(ProducerConfig)
 	}
 
 	// 不存在则创建新的 producer
-	opts.Topic = topic
-	producer, err := bus.client.CreateProducer(opts)
+	producer := &Producer{
+		config: cfg,
+	}
+
+	var err error
+	producer.Producer, err = bus.client.CreateProducer(cfg.options(topic))
 	if err != nil {
 		return nil, err
 	}
@@ -94,13 +232,8 @@ func (bus *Pulbus) getProducer(topic string, opts pulsar.ProducerOptions) (pulsa
 	return producer, nil
 }
 
-// Send 发送消息到指定 Topic
-func (bus *Pulbus) Send(ctx context.Context, topic string, messageOpts ...ProducerOption) error {
-	producer, err := bus.getProducer(topic, pulsar.ProducerOptions{})
-	if err != nil {
-		return err
-	}
-
+// buildMessage 使用选项构建消息
+func buildMessage(messageOpts ...ProducerOption) (*pulsar.ProducerMessage, error) {
 	msg := &pulsar.ProducerMessage{}
 
 	// 应用自定义选项
@@ -109,11 +242,30 @@ func (bus *Pulbus) Send(ctx context.Context, topic string, messageOpts ...Produc
 	}
 
 	// 判定消息内容是否为空
-	if msg.Payload == nil || msg.Value == nil {
-		return errors.New("消息内容不能为空")
+	if msg.Payload == nil && msg.Value == nil {
+		return nil, ErrEmptyPayload
 	}
 
+	return msg, nil
+}
+
+// Send 发送消息到指定 Topic，context 中的链路上下文写入消息属性
+func (bus *Pulbus) Send(ctx context.Context, topic string, messageOpts ...ProducerOption) error {
+	msg, err := buildMessage(messageOpts...)
+	if err != nil {
+		return err
+	}
+	bus.inject(ctx, msg)
+
+	var producer *Producer
+	producer, err = bus.getProducer(topic)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
 	_, err = producer.Send(ctx, msg)
+	bus.metricsHook().ObservePublish(bus.ResolveTopic(topic), time.Since(start), err)
 	return err
 }
 
@@ -121,3 +273,38 @@ func (bus *Pulbus) Send(ctx context.Context, topic string, messageOpts ...Produc
 func (bus *Pulbus) SendBytes(ctx context.Context, topic string, b []byte, messageOpts ...ProducerOption) error {
 	return bus.Send(ctx, topic, append(messageOpts, WithPayload(b))...)
 }
+
+// SendCallback 异步发送回调函数类型
+type SendCallback func(id pulsar.MessageID, msg *pulsar.ProducerMessage, err error)
+
+// SendAsync 异步发送消息到指定 Topic，发送完成后调用 callback
+// 消息构建或 producer 创建失败时直接调用 callback 返回错误
+//
+// 使用示例:
+//
+//	bus.SendAsync(ctx, "events", func(id pulsar.MessageID, msg *pulsar.ProducerMessage, err error) {
+//	    if err != nil {
+//	        zap.L().Error("发送失败", zap.Error(err))
+//	    }
+//	}, WithPayload(data))
+func (bus *Pulbus) SendAsync(ctx context.Context, topic string, callback SendCallback, messageOpts ...ProducerOption) {
+	msg, err := buildMessage(messageOpts...)
+	if err != nil {
+		callback(nil, msg, err)
+		return
+	}
+	bus.inject(ctx, msg)
+
+	var producer *Producer
+	producer, err = bus.getProducer(topic)
+	if err != nil {
+		callback(nil, msg, err)
+		return
+	}
+
+	start := time.Now()
+	producer.SendAsync(ctx, msg, func(id pulsar.MessageID, msg *pulsar.ProducerMessage, err error) {
+		bus.metricsHook().ObservePublish(bus.ResolveTopic(topic), time.Since(start), err)
+		callback(id, msg, err)
+	})
+}
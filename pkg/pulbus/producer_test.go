@@ -5,6 +5,9 @@
 package pulbus
 
 import (
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -38,3 +41,124 @@ func TestProducerOptions(t *testing.T) {
 	require.NotNil(t, msg.SequenceID)
 	require.Equal(t, *msg.SequenceID, seqID)
 }
+
+func TestProducerMessageOptions(t *testing.T) {
+	eventTime := time.Now()
+	deliverAt := eventTime.Add(time.Hour)
+
+	msg, err := buildMessage(
+		WithPayload([]byte("test")),
+		WithProperties(map[string]string{"a": "1"}),
+		WithProperties(map[string]string{"b": "2"}),
+		WithEventTime(eventTime),
+		WithDeliverAt(deliverAt),
+	)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, msg.Properties)
+	require.Equal(t, eventTime, msg.EventTime)
+	require.Equal(t, deliverAt, msg.DeliverAt)
+
+	_, err = buildMessage(WithProducerKey("key"))
+	require.ErrorIs(t, err, ErrEmptyPayload)
+}
+
+func TestProducerConfig(t *testing.T) {
+	cfg := ProducerConfig{
+		BatchingMaxMessages:     500,
+		BatchingMaxPublishDelay: 50 * time.Millisecond,
+		CompressionType:         pulsar.LZ4,
+		MaxPendingMessages:      1000,
+		SendTimeout:             5 * time.Second,
+		AccessMode:              pulsar.ProducerAccessModeExclusive,
+	}
+
+	opts := cfg.options("events")
+	require.Equal(t, "events", opts.Topic)
+	require.Equal(t, uint(500), opts.BatchingMaxMessages)
+	require.Equal(t, pulsar.LZ4, opts.CompressionType)
+	require.Equal(t, pulsar.ProducerAccessModeExclusive, opts.ProducerAccessMode)
+
+	bus := &Pulbus{}
+	WithProducerConfig("events", cfg)(bus)
+	require.NoError(t, bus.ConfigureProducer("events", cfg))
+
+	// producer 已创建后使用不同的配置
+	bus.producers.Store("events", &Producer{config: cfg})
+	require.NoError(t, bus.ConfigureProducer("events", cfg))
+	require.ErrorIs(t, bus.ConfigureProducer("events", ProducerConfig{}), ErrProducerOptionsConflict)
+}
+
+// countingClient 统计 producer 和 consumer 的创建次数
+type countingClient struct {
+	pulsar.Client
+
+	producers  atomic.Int32
+	subscribes atomic.Int32
+}
+
+func (c *countingClient) CreateProducer(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+	c.producers.Add(1)
+	// 放大并发创建的时间窗口
+	time.Sleep(10 * time.Millisecond)
+	return c.Client.CreateProducer(options)
+}
+
+func (c *countingClient) Subscribe(options pulsar.ConsumerOptions) (pulsar.Consumer, error) {
+	c.subscribes.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return c.Client.Subscribe(options)
+}
+
+// sliceSchema 不可比较的 Schema 实现
+type sliceSchema struct {
+	pulsar.Schema
+
+	definitions []string
+}
+
+func (s sliceSchema) GetSchemaInfo() *pulsar.SchemaInfo {
+	return &pulsar.SchemaInfo{Type: pulsar.JSON, Schema: strings.Join(s.definitions, "")}
+}
+
+func TestGetProducerConcurrent(t *testing.T) {
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	client := &countingClient{Client: bus.client}
+	bus.client = client
+
+	var wg sync.WaitGroup
+	producers := make([]*Producer, 10)
+	for i := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := bus.getProducer("events")
+			require.NoError(t, err)
+			producers[i] = p
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), client.producers.Load())
+	for _, p := range producers {
+		require.Same(t, producers[0], p)
+	}
+}
+
+func TestProducerConfigSchema(t *testing.T) {
+	definition := `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`
+	a := ProducerConfig{Schema: pulsar.NewJSONSchema(definition, nil)}
+	b := ProducerConfig{Schema: pulsar.NewJSONSchema(definition, nil)}
+	require.True(t, a.equal(b))
+	require.False(t, a.equal(ProducerConfig{}))
+	require.False(t, a.equal(ProducerConfig{Schema: pulsar.NewAvroSchema(definition, nil)}))
+
+	// 不可比较的 Schema 不会 panic
+	c := ProducerConfig{Schema: sliceSchema{definitions: []string{definition}}}
+	require.NotPanics(t, func() {
+		require.True(t, c.equal(ProducerConfig{Schema: sliceSchema{definitions: []string{definition}}}))
+	})
+}
@@ -0,0 +1,484 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+)
+
+var (
+	ErrAdminNotConfigured     = errors.New("未配置 Pulsar Admin，请使用 WithAdmin 配置")
+	ErrInvalidNamespace       = errors.New("namespace 格式错误，应为 tenant/namespace")
+	ErrPartitionsDecrease     = errors.New("分区数只能增加，不能减少")
+	ErrTopicPartitionMismatch = errors.New("已存在的 Topic 分区类型与配置不一致")
+)
+
+// ProvisionSpec 声明式资源配置，可嵌入服务配置中通过 configure.Load 从 YAML 加载
+//
+// 配置示例:
+//
+//	pulsar:
+//	  tenants:
+//	    - name: production
+//	      adminRoles: [admin]
+//	  namespaces:
+//	    - name: production/app
+//	      retention:
+//	        time: 168h
+//	        sizeMB: 1024
+//	      ttl: 72h
+//	      topics:
+//	        - name: orders
+//	          partitions: 4
+//	          subscriptions: [order-sub]
+type ProvisionSpec struct {
+	Tenants    []TenantSpec    // 租户，namespace 引用但未声明的租户会使用默认配置创建
+	Namespaces []NamespaceSpec // 命名空间
+}
+
+// TenantSpec 租户配置
+type TenantSpec struct {
+	Name            string   // 租户名称
+	AdminRoles      []string // 管理员角色
+	AllowedClusters []string // 允许的集群，为空时使用全部集群
+}
+
+// NamespaceSpec 命名空间配置
+type NamespaceSpec struct {
+	Name      string         // 完整名称，例如 nexa/production，可使用 EnvironmentTenantNamespace 生成
+	Retention *RetentionSpec // 保留策略，为空时不管理
+	TTL       time.Duration  // 消息 TTL，为 0 时不管理
+	Topics    []TopicSpec    // Topic
+}
+
+// RetentionSpec 保留策略，负数表示无限制
+type RetentionSpec struct {
+	Time   time.Duration // 保留时间，精确到分钟
+	SizeMB int64         // 保留大小
+}
+
+// policies 转换为 pulsar 保留策略
+func (r RetentionSpec) policies() utils.RetentionPolicies {
+	minutes := -1
+	if r.Time >= 0 {
+		minutes = int(r.Time / time.Minute)
+	}
+	return utils.RetentionPolicies{
+		RetentionTimeInMinutes: minutes,
+		RetentionSizeInMB:      r.SizeMB,
+	}
+}
+
+// TopicSpec Topic 配置
+type TopicSpec struct {
+	Name          string   // Topic 名称，不含 tenant 和 namespace
+	Partitions    int      // 分区数，为 0 时创建非分区 Topic
+	Subscriptions []string // 订阅，不存在时从最早的消息开始创建
+}
+
+// ProvisionAction 变更类型
+type ProvisionAction string
+
+const (
+	ProvisionCreate ProvisionAction = "create"
+	ProvisionUpdate ProvisionAction = "update"
+)
+
+// ProvisionChange 资源变更
+type ProvisionChange struct {
+	Action   ProvisionAction
+	Kind     string // tenant / namespace / retention / ttl / topic / partitions / subscription
+	Resource string // 资源名称
+	From     string // 变更前的值，创建时为空
+	To       string // 变更后的值
+}
+
+func (c ProvisionChange) String() string {
+	if c.Action == ProvisionCreate {
+		if c.To == "" {
+			return fmt.Sprintf("+ %s %s", c.Kind, c.Resource)
+		}
+		return fmt.Sprintf("+ %s %s (%s)", c.Kind, c.Resource, c.To)
+	}
+	return fmt.Sprintf("~ %s %s: %s -> %s", c.Kind, c.Resource, c.From, c.To)
+}
+
+// ProvisionDiff 资源变更列表
+type ProvisionDiff []ProvisionChange
+
+func (d ProvisionDiff) String() string {
+	lines := make([]string, len(d))
+	for i, c := range d {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ProvisionOption 资源配置选项
+type ProvisionOption func(o *provisionOptions)
+
+type provisionOptions struct {
+	dryRun bool
+}
+
+// WithProvisionDryRun 仅计算变更，不修改任何资源
+func WithProvisionDryRun() ProvisionOption {
+	return func(o *provisionOptions) {
+		o.dryRun = true
+	}
+}
+
+// Provision 确保配置中的资源存在，返回实际执行（或 dry-run 时将要执行）的变更
+//
+// 使用示例:
+//
+//	diff, err := bus.Provision(ctx, cfg.Pulsar, WithProvisionDryRun())
+//	fmt.Println(diff)
+func (bus *Pulbus) Provision(ctx context.Context, spec ProvisionSpec, opts ...ProvisionOption) (ProvisionDiff, error) {
+	if bus.admin == nil {
+		return nil, ErrAdminNotConfigured
+	}
+	return bus.admin.Provision(ctx, spec, opts...)
+}
+
+// Provision 确保配置中的资源存在，返回实际执行（或 dry-run 时将要执行）的变更
+// 资源只会创建或更新，不会删除配置中不存在的资源
+func (admin *Admin) Provision(ctx context.Context, spec ProvisionSpec, opts ...ProvisionOption) (ProvisionDiff, error) {
+	o := &provisionOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	p := &provisioner{admin: admin, ctx: ctx, dryRun: o.dryRun}
+
+	// 合并 namespace 引用的租户
+	tenants := slices.Clone(spec.Tenants)
+	for _, ns := range spec.Namespaces {
+		tenant, _, ok := strings.Cut(ns.Name, "/")
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidNamespace, ns.Name)
+		}
+		if !slices.ContainsFunc(tenants, func(t TenantSpec) bool { return t.Name == tenant }) {
+			tenants = append(tenants, TenantSpec{Name: tenant})
+		}
+	}
+
+	for _, tenant := range tenants {
+		if err := p.tenant(tenant); err != nil {
+			return p.diff, err
+		}
+	}
+
+	for _, ns := range spec.Namespaces {
+		if err := p.namespace(ns); err != nil {
+			return p.diff, err
+		}
+	}
+
+	return p.diff, nil
+}
+
+// provisioner 逐项比较并应用资源配置
+type provisioner struct {
+	admin  *Admin
+	ctx    context.Context
+	dryRun bool
+	diff   ProvisionDiff
+}
+
+// apply 记录变更，非 dry-run 时执行
+func (p *provisioner) apply(change ProvisionChange, fn func() error) error {
+	if !p.dryRun {
+		if err := fn(); err != nil {
+			return fmt.Errorf("%s %s %s 失败: %w", change.Action, change.Kind, change.Resource, err)
+		}
+	}
+	p.diff = append(p.diff, change)
+	return nil
+}
+
+func (p *provisioner) tenant(spec TenantSpec) error {
+	names, err := p.admin.Tenants().ListWithContext(p.ctx)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(names, spec.Name) {
+		data := utils.TenantData{Name: spec.Name, AdminRoles: spec.AdminRoles, AllowedClusters: spec.AllowedClusters}
+		if len(data.AllowedClusters) == 0 {
+			data.AllowedClusters, err = p.admin.Clusters().ListWithContext(p.ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		return p.apply(ProvisionChange{Action: ProvisionCreate, Kind: "tenant", Resource: spec.Name}, func() error {
+			return p.admin.Tenants().CreateWithContext(p.ctx, data)
+		})
+	}
+
+	if len(spec.AdminRoles) == 0 && len(spec.AllowedClusters) == 0 {
+		return nil
+	}
+
+	var data utils.TenantData
+	data, err = p.admin.Tenants().GetWithContext(p.ctx, spec.Name)
+	if err != nil {
+		return err
+	}
+
+	from := fmt.Sprintf("roles=%v clusters=%v", data.AdminRoles, data.AllowedClusters)
+	changed := false
+	if len(spec.AdminRoles) > 0 && !sameElements(data.AdminRoles, spec.AdminRoles) {
+		data.AdminRoles = spec.AdminRoles
+		changed = true
+	}
+	if len(spec.AllowedClusters) > 0 && !sameElements(data.AllowedClusters, spec.AllowedClusters) {
+		data.AllowedClusters = spec.AllowedClusters
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	data.Name = spec.Name
+	return p.apply(ProvisionChange{
+		Action:   ProvisionUpdate,
+		Kind:     "tenant",
+		Resource: spec.Name,
+		From:     from,
+		To:       fmt.Sprintf("roles=%v clusters=%v", data.AdminRoles, data.AllowedClusters),
+	}, func() error {
+		return p.admin.Tenants().UpdateWithContext(p.ctx, data)
+	})
+}
+
+func (p *provisioner) namespace(spec NamespaceSpec) error {
+	tenant, _, _ := strings.Cut(spec.Name, "/")
+
+	// dry-run 时租户可能尚未创建
+	var exists bool
+	if !p.dryRun || !p.created("tenant", tenant) {
+		names, err := p.admin.Namespaces().GetNamespacesWithContext(p.ctx, tenant)
+		if err != nil {
+			return err
+		}
+		exists = slices.Contains(names, spec.Name)
+	}
+
+	if !exists {
+		err := p.apply(ProvisionChange{Action: ProvisionCreate, Kind: "namespace", Resource: spec.Name}, func() error {
+			return p.admin.Namespaces().CreateNamespaceWithContext(p.ctx, spec.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// dry-run 时新建的 namespace 无法查询，所有配置均为创建
+	fresh := !exists && p.dryRun
+
+	if spec.Retention != nil {
+		if err := p.retention(spec.Name, spec.Retention.policies(), fresh); err != nil {
+			return err
+		}
+	}
+
+	if spec.TTL > 0 {
+		if err := p.ttl(spec.Name, int(spec.TTL/time.Second), fresh); err != nil {
+			return err
+		}
+	}
+
+	var partitioned, nonPartitioned []string
+	if !fresh {
+		ns, err := utils.GetNamespaceName(spec.Name)
+		if err != nil {
+			return err
+		}
+
+		partitioned, nonPartitioned, err = p.admin.Topics().ListWithContext(p.ctx, *ns)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, topic := range spec.Topics {
+		if err := p.topic(spec.Name, topic, partitioned, nonPartitioned); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *provisioner) retention(namespace string, policies utils.RetentionPolicies, fresh bool) error {
+	change := ProvisionChange{
+		Action:   ProvisionCreate,
+		Kind:     "retention",
+		Resource: namespace,
+		To:       formatRetention(policies),
+	}
+
+	if !fresh {
+		current, err := p.admin.Namespaces().GetRetentionWithContext(p.ctx, namespace)
+		if err != nil {
+			return err
+		}
+		if current != nil && *current == policies {
+			return nil
+		}
+		// 未设置时返回零值
+		if current != nil && *current != (utils.RetentionPolicies{}) {
+			change.Action = ProvisionUpdate
+			change.From = formatRetention(*current)
+		}
+	}
+
+	return p.apply(change, func() error {
+		return p.admin.Namespaces().SetRetentionWithContext(p.ctx, namespace, policies)
+	})
+}
+
+func (p *provisioner) ttl(namespace string, seconds int, fresh bool) error {
+	change := ProvisionChange{
+		Action:   ProvisionCreate,
+		Kind:     "ttl",
+		Resource: namespace,
+		To:       (time.Duration(seconds) * time.Second).String(),
+	}
+
+	if !fresh {
+		current, err := p.admin.Namespaces().GetNamespaceMessageTTLWithContext(p.ctx, namespace)
+		if err != nil {
+			return err
+		}
+		if current == seconds {
+			return nil
+		}
+		// 未设置时返回 -1
+		if current > 0 {
+			change.Action = ProvisionUpdate
+			change.From = (time.Duration(current) * time.Second).String()
+		}
+	}
+
+	return p.apply(change, func() error {
+		return p.admin.Namespaces().SetNamespaceMessageTTLWithContext(p.ctx, namespace, seconds)
+	})
+}
+
+func (p *provisioner) topic(namespace string, spec TopicSpec, partitioned, nonPartitioned []string) error {
+	tenant, ns, _ := strings.Cut(namespace, "/")
+	fullName := NewTopicBuilder(tenant, ns).Build(spec.Name)
+
+	topicName, err := utils.GetTopicName(fullName)
+	if err != nil {
+		return err
+	}
+
+	isPartitioned := slices.Contains(partitioned, fullName)
+	isNonPartitioned := slices.Contains(nonPartitioned, fullName)
+
+	switch {
+	case !isPartitioned && !isNonPartitioned:
+		err = p.apply(ProvisionChange{Action: ProvisionCreate, Kind: "topic", Resource: fullName, To: fmt.Sprintf("partitions=%d", spec.Partitions)}, func() error {
+			return p.admin.Topics().CreateWithContext(p.ctx, *topicName, spec.Partitions)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range spec.Subscriptions {
+			if err = p.subscription(topicName, sub); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case isPartitioned != (spec.Partitions > 0):
+		return fmt.Errorf("%w: %s", ErrTopicPartitionMismatch, fullName)
+
+	case isPartitioned:
+		var meta utils.PartitionedTopicMetadata
+		meta, err = p.admin.Topics().GetMetadataWithContext(p.ctx, *topicName)
+		if err != nil {
+			return err
+		}
+
+		if spec.Partitions < meta.Partitions {
+			return fmt.Errorf("%w: %s %d -> %d", ErrPartitionsDecrease, fullName, meta.Partitions, spec.Partitions)
+		}
+
+		if spec.Partitions > meta.Partitions {
+			err = p.apply(ProvisionChange{
+				Action:   ProvisionUpdate,
+				Kind:     "partitions",
+				Resource: fullName,
+				From:     fmt.Sprint(meta.Partitions),
+				To:       fmt.Sprint(spec.Partitions),
+			}, func() error {
+				return p.admin.Topics().UpdateWithContext(p.ctx, *topicName, spec.Partitions)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(spec.Subscriptions) == 0 {
+		return nil
+	}
+
+	var subs []string
+	subs, err = p.admin.Subscriptions().ListWithContext(p.ctx, *topicName)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range spec.Subscriptions {
+		if slices.Contains(subs, sub) {
+			continue
+		}
+		if err = p.subscription(topicName, sub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *provisioner) subscription(topic *utils.TopicName, sub string) error {
+	return p.apply(ProvisionChange{Action: ProvisionCreate, Kind: "subscription", Resource: topic.String() + "/" + sub}, func() error {
+		return p.admin.Subscriptions().CreateWithContext(p.ctx, *topic, sub, utils.Earliest)
+	})
+}
+
+// created 是否已记录资源的创建
+func (p *provisioner) created(kind, resource string) bool {
+	return slices.ContainsFunc(p.diff, func(c ProvisionChange) bool {
+		return c.Action == ProvisionCreate && c.Kind == kind && c.Resource == resource
+	})
+}
+
+// formatRetention 格式化保留策略
+func formatRetention(policies utils.RetentionPolicies) string {
+	return fmt.Sprintf("time=%dm size=%dMB", policies.RetentionTimeInMinutes, policies.RetentionSizeInMB)
+}
+
+// sameElements 比较两个字符串切片元素是否相同，忽略顺序
+func sameElements(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
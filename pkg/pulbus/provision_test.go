@@ -0,0 +1,253 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsaradmin"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/admin"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/stretchr/testify/require"
+
+	"nexis.run/nexa/kit/configure"
+)
+
+// stubAdmin 内存中的 Pulsar Admin，仅实现资源配置用到的接口
+type stubAdmin struct {
+	admin.Client
+
+	tenants    map[string]utils.TenantData
+	namespaces map[string]*stubNamespace
+	topics     map[string]int // 非分区 Topic 为 0
+	subs       map[string][]string
+}
+
+type stubNamespace struct {
+	retention utils.RetentionPolicies
+	ttl       int
+}
+
+func newStubAdmin() *stubAdmin {
+	return &stubAdmin{
+		tenants:    make(map[string]utils.TenantData),
+		namespaces: make(map[string]*stubNamespace),
+		topics:     make(map[string]int),
+		subs:       make(map[string][]string),
+	}
+}
+
+func (a *stubAdmin) Clusters() admin.Clusters           { return stubClusters{} }
+func (a *stubAdmin) Tenants() admin.Tenants             { return stubTenants{a: a} }
+func (a *stubAdmin) Namespaces() admin.Namespaces       { return stubNamespaces{a: a} }
+func (a *stubAdmin) Topics() admin.Topics               { return stubTopics{a: a} }
+func (a *stubAdmin) Subscriptions() admin.Subscriptions { return stubSubscriptions{a: a} }
+
+type stubClusters struct{ admin.Clusters }
+
+func (stubClusters) ListWithContext(context.Context) ([]string, error) {
+	return []string{"standalone"}, nil
+}
+
+type stubTenants struct {
+	admin.Tenants
+	a *stubAdmin
+}
+
+func (s stubTenants) ListWithContext(context.Context) (names []string, _ error) {
+	for name := range s.a.tenants {
+		names = append(names, name)
+	}
+	return
+}
+
+func (s stubTenants) GetWithContext(_ context.Context, name string) (utils.TenantData, error) {
+	return s.a.tenants[name], nil
+}
+
+func (s stubTenants) CreateWithContext(_ context.Context, data utils.TenantData) error {
+	s.a.tenants[data.Name] = data
+	return nil
+}
+
+func (s stubTenants) UpdateWithContext(_ context.Context, data utils.TenantData) error {
+	s.a.tenants[data.Name] = data
+	return nil
+}
+
+type stubNamespaces struct {
+	admin.Namespaces
+	a *stubAdmin
+}
+
+func (s stubNamespaces) GetNamespacesWithContext(context.Context, string) (names []string, _ error) {
+	for name := range s.a.namespaces {
+		names = append(names, name)
+	}
+	return
+}
+
+func (s stubNamespaces) CreateNamespaceWithContext(_ context.Context, namespace string) error {
+	s.a.namespaces[namespace] = &stubNamespace{ttl: -1}
+	return nil
+}
+
+func (s stubNamespaces) GetRetentionWithContext(_ context.Context, namespace string) (*utils.RetentionPolicies, error) {
+	policies := s.a.namespaces[namespace].retention
+	return &policies, nil
+}
+
+func (s stubNamespaces) SetRetentionWithContext(_ context.Context, namespace string, policies utils.RetentionPolicies) error {
+	s.a.namespaces[namespace].retention = policies
+	return nil
+}
+
+func (s stubNamespaces) GetNamespaceMessageTTLWithContext(_ context.Context, namespace string) (int, error) {
+	return s.a.namespaces[namespace].ttl, nil
+}
+
+func (s stubNamespaces) SetNamespaceMessageTTLWithContext(_ context.Context, namespace string, ttl int) error {
+	s.a.namespaces[namespace].ttl = ttl
+	return nil
+}
+
+type stubTopics struct {
+	admin.Topics
+	a *stubAdmin
+}
+
+func (s stubTopics) ListWithContext(context.Context, utils.NameSpaceName) (partitioned, nonPartitioned []string, _ error) {
+	for name, n := range s.a.topics {
+		if n > 0 {
+			partitioned = append(partitioned, name)
+		} else {
+			nonPartitioned = append(nonPartitioned, name)
+		}
+	}
+	return
+}
+
+func (s stubTopics) CreateWithContext(_ context.Context, topic utils.TopicName, partitions int) error {
+	s.a.topics[topic.String()] = partitions
+	return nil
+}
+
+func (s stubTopics) UpdateWithContext(_ context.Context, topic utils.TopicName, partitions int) error {
+	s.a.topics[topic.String()] = partitions
+	return nil
+}
+
+func (s stubTopics) GetMetadataWithContext(_ context.Context, topic utils.TopicName) (utils.PartitionedTopicMetadata, error) {
+	return utils.PartitionedTopicMetadata{Partitions: s.a.topics[topic.String()]}, nil
+}
+
+type stubSubscriptions struct {
+	admin.Subscriptions
+	a *stubAdmin
+}
+
+func (s stubSubscriptions) ListWithContext(_ context.Context, topic utils.TopicName) ([]string, error) {
+	return s.a.subs[topic.String()], nil
+}
+
+func (s stubSubscriptions) CreateWithContext(_ context.Context, topic utils.TopicName, sub string, _ utils.MessageID) error {
+	s.a.subs[topic.String()] = append(s.a.subs[topic.String()], sub)
+	return nil
+}
+
+func TestProvisionSpecLoad(t *testing.T) {
+	type config struct {
+		configure.Configure
+		Pulsar ProvisionSpec
+	}
+
+	c, err := configure.Load[config]("testdata/provision.yaml")
+	require.NoError(t, err)
+
+	require.Equal(t, "production", c.Pulsar.Tenants[0].Name)
+	require.Equal(t, []string{"admin"}, c.Pulsar.Tenants[0].AdminRoles)
+	require.Equal(t, "production/app", c.Pulsar.Namespaces[0].Name)
+	require.Equal(t, &RetentionSpec{Time: 168 * time.Hour, SizeMB: 1024}, c.Pulsar.Namespaces[0].Retention)
+	require.Equal(t, 72*time.Hour, c.Pulsar.Namespaces[0].TTL)
+	require.Equal(t, TopicSpec{Name: "orders", Partitions: 4, Subscriptions: []string{"order-sub"}}, c.Pulsar.Namespaces[0].Topics[0])
+}
+
+func TestProvision(t *testing.T) {
+	stub := newStubAdmin()
+	bus := &Pulbus{admin: &Admin{Client: stub}}
+	ctx := context.Background()
+
+	spec := ProvisionSpec{
+		Namespaces: []NamespaceSpec{{
+			Name:      "production/app",
+			Retention: &RetentionSpec{Time: 7 * 24 * time.Hour, SizeMB: -1},
+			TTL:       time.Hour,
+			Topics: []TopicSpec{
+				{Name: "orders", Partitions: 2, Subscriptions: []string{"order-sub"}},
+				{Name: "events"},
+			},
+		}},
+	}
+
+	// dry-run 不修改资源
+	diff, err := bus.Provision(ctx, spec, WithProvisionDryRun())
+	require.NoError(t, err)
+	require.Equal(t, `+ tenant production
++ namespace production/app
++ retention production/app (time=10080m size=-1MB)
++ ttl production/app (1h0m0s)
++ topic persistent://production/app/orders (partitions=2)
++ subscription persistent://production/app/orders/order-sub
++ topic persistent://production/app/events (partitions=0)`, diff.String())
+	require.Empty(t, stub.tenants)
+
+	applied, err := bus.Provision(ctx, spec)
+	require.NoError(t, err)
+	require.Equal(t, diff, applied)
+	require.Equal(t, []string{"standalone"}, stub.tenants["production"].AllowedClusters)
+	require.Equal(t, 3600, stub.namespaces["production/app"].ttl)
+	require.Equal(t, []string{"order-sub"}, stub.subs["persistent://production/app/orders"])
+
+	// 已存在的资源不产生变更
+	diff, err = bus.Provision(ctx, spec)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	// 增加分区、更新 TTL 和订阅
+	spec.Namespaces[0].TTL = 2 * time.Hour
+	spec.Namespaces[0].Topics[0].Partitions = 4
+	spec.Namespaces[0].Topics[0].Subscriptions = append(spec.Namespaces[0].Topics[0].Subscriptions, "audit-sub")
+	diff, err = bus.Provision(ctx, spec)
+	require.NoError(t, err)
+	require.Equal(t, `~ ttl production/app: 1h0m0s -> 2h0m0s
+~ partitions persistent://production/app/orders: 2 -> 4
++ subscription persistent://production/app/orders/audit-sub`, diff.String())
+
+	// 分区只能增加
+	spec.Namespaces[0].Topics[0].Partitions = 1
+	_, err = bus.Provision(ctx, spec)
+	require.ErrorIs(t, err, ErrPartitionsDecrease)
+
+	// 分区类型不一致
+	spec.Namespaces[0].Topics[0].Partitions = 4
+	spec.Namespaces[0].Topics[1].Partitions = 2
+	_, err = bus.Provision(ctx, spec)
+	require.ErrorIs(t, err, ErrTopicPartitionMismatch)
+
+	_, err = bus.Provision(ctx, ProvisionSpec{Namespaces: []NamespaceSpec{{Name: "app"}}})
+	require.ErrorIs(t, err, ErrInvalidNamespace)
+
+	_, err = (&Pulbus{}).Provision(ctx, spec)
+	require.ErrorIs(t, err, ErrAdminNotConfigured)
+}
+
+func TestNewAdminError(t *testing.T) {
+	_, err := New("pulsar://localhost:6650", WithAdmin("http://localhost:8080", func(cfg *pulsaradmin.Config) {
+		cfg.TLSTrustCertsFilePath = "testdata/missing.pem"
+	}))
+	require.Error(t, err)
+}
@@ -5,81 +5,166 @@
 package pulbus
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
-	"go.uber.org/zap"
+	"go.opentelemetry.io/otel/propagation"
+
+	"nexis.run/nexa/kit"
+)
+
+var (
+	ErrInvalidEnvironment = errors.New("无效的环境")
+	ErrBusClosed          = errors.New("消息总线已关闭")
 )
 
+// Bus 消息总线接口，Pulbus 和 NewMemory 创建的内存实现均满足该接口
+// 业务代码依赖 Bus 时可以在单元测试中替换为内存实现
+type Bus interface {
+	// Send 发送消息
+	Send(ctx context.Context, topic string, messageOpts ...ProducerOption) error
+	// Consume 消费消息，阻塞直到 context 取消
+	Consume(ctx context.Context, topic, subscription string, handler MessageHandler, opts ...ConsumerOption) error
+	// ConsumeContext 消费消息，handler 的 context 携带消息中的链路上下文，阻塞直到 context 取消
+	ConsumeContext(ctx context.Context, topic, subscription string, handler ContextHandler, opts ...ConsumerOption) error
+	// ConsumeBatch 批量消费消息，阻塞直到 context 取消
+	ConsumeBatch(ctx context.Context, topic, subscription string, handler BatchHandler, opts ...ConsumerOption) error
+	// Close 关闭总线
+	Close() error
+}
+
+var _ Bus = (*Pulbus)(nil)
+
 type Pulbus struct {
-	client pulsar.Client
-	admin  *Admin
+	client        pulsar.Client
+	clientOptions pulsar.ClientOptions
+	admin         *Admin
+
+	adminURL     string        // Admin web service 地址
+	adminOptions []AdminOption // Admin 配置选项
+
+	txnTimeout time.Duration // 事务超时时间
+
+	tenant      string          // Topic 租户
+	environment kit.Environment // Topic 环境
+
+	propagator propagation.TextMapPropagator // 链路上下文传播方式
+
+	middlewares []ConsumerMiddleware // 所有消费者共用的中间件
 
-	producers sync.Map // map[Topic]pulsar.Producer - 缓存 producer，避免重复创建
-	consumers sync.Map // map[ConsumerKey]pulsar.Consumer - 缓存 consumer，避免重复创建
+	metrics         Metrics       // 指标收集
+	backlogInterval time.Duration // 订阅积压查询间隔
+	done            chan struct{} // 停止信号
+
+	lifecycle sync.RWMutex // 保护 stopped，保证停止后不再开始消费
+	stopped   bool         // 是否已停止接收消息
+	closeOnce sync.Once    // 保证连接只关闭一次
+
+	requestTimeout time.Duration  // Request 默认超时时间
+	replies        replies        // Request 响应监听
+	replyProducers replyProducers // Reply 响应 producer 缓存
+
+	producers       sync.Map   // map[Topic]*Producer - 缓存 producer，避免重复创建
+	producerLocks   keyedMutex // 保证同一 Topic 的 producer 只创建一次
+	producerConfigs sync.Map   // map[Topic]ProducerConfig - producer 配置
+	consumers       sync.Map   // map[ConsumerKey]*Consumer - 缓存 consumer，避免重复创建
+	consumerLocks   keyedMutex // 保证同一 ConsumerKey 的 consumer 只创建一次
+	deliveries      sync.Map   // map[pulsar.Message]*delivery - 处理中的消息
+}
+
+// keyedMutex 按 key 加锁
+type keyedMutex struct {
+	locks sync.Map // map[key]*sync.Mutex
+}
+
+// lock 锁定 key 并返回解锁函数
+func (m *keyedMutex) lock(key any) func() {
+	v, _ := m.locks.LoadOrStore(key, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
 }
 
 // Option Pulbus 配置选项
 type Option func(bus *Pulbus)
 
-// WithAdmin 配置 Pulsar Admin 客户端
+// WithAdmin 配置 Pulsar Admin 客户端，创建失败时 New 返回错误
 func WithAdmin(webServiceURL string, opts ...AdminOption) Option {
 	return func(bus *Pulbus) {
-		admin, err := NewAdmin(webServiceURL, opts...)
-		if err != nil {
-			zap.L().Error("Pulsar Admin 创建失败", zap.String("webServiceURL", webServiceURL), zap.Error(err))
-			return
-		}
-
-		bus.admin = admin
+		bus.adminURL = webServiceURL
+		bus.adminOptions = opts
 	}
 }
 
 func New(bookie string, opts ...Option) (bus *Pulbus, err error) {
-	var client pulsar.Client
-	client, err = pulsar.NewClient(pulsar.ClientOptions{
-		URL: bookie,
-	})
-
-	if err != nil {
-		return
-	}
-
 	bus = &Pulbus{
-		client:    client,
+		clientOptions: pulsar.ClientOptions{
+			URL: bookie,
+		},
+		done:      make(chan struct{}),
 		producers: sync.Map{},
 		consumers: sync.Map{},
 	}
 
-	// 应用选项
+	// 应用选项，client 配置需要在创建 client 前设置
 	for _, opt := range opts {
 		opt(bus)
 	}
 
+	if bus.environment != "" && !bus.environment.IsValid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEnvironment, bus.environment)
+	}
+
+	if bus.adminURL != "" {
+		bus.admin, err = NewAdmin(bus.adminURL, bus.adminOptions...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bus.client, err = pulsar.NewClient(bus.clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// 定时记录订阅积压
+	if bus.metrics != nil && bus.admin != nil {
+		go bus.reportBacklogLoop()
+	}
+
 	return bus, nil
 }
 
-// Close 关闭所有 producers、consumers 和 client
+// Close 立即关闭所有 producers、consumers 和 client，不等待处理中的消息，需要等待时使用 Shutdown
 func (bus *Pulbus) Close() error {
-	// 关闭所有 producers
-	bus.producers.Range(func(key, value interface{}) bool {
-		if producer, ok := value.(pulsar.Producer); ok {
-			producer.Close()
-		}
-		return true
-	})
+	bus.stop()
+	bus.close()
+	return nil
+}
 
-	// 关闭所有 consumers
-	bus.consumers.Range(func(key, value interface{}) bool {
-		if consumer, ok := value.(pulsar.Consumer); ok {
-			consumer.Close()
-		}
-		return true
+// close 关闭所有 producers、consumers 和 client
+func (bus *Pulbus) close() {
+	bus.closeOnce.Do(func() {
+		// 关闭所有 producers
+		bus.producers.Range(func(key, value interface{}) bool {
+			value.(*Producer).Close()
+			return true
+		})
+		bus.replyProducers.close()
+
+		// 关闭所有 consumers
+		bus.consumers.Range(func(key, value interface{}) bool {
+			value.(*Consumer).Close()
+			return true
+		})
+
+		// 关闭 client
+		bus.client.Close()
 	})
-
-	// 关闭 client
-	bus.client.Close()
-	return nil
 }
 
 // GetAdmin 获取 Pulsar Admin 客户端
@@ -0,0 +1,247 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// PropertyReplyTo 请求消息的响应 Topic 属性名
+	PropertyReplyTo = "reply-to"
+
+	// PropertyCorrelationID 请求和响应的关联 ID 属性名
+	PropertyCorrelationID = "correlation-id"
+
+	// PropertyRequestDeadline 请求截止时间属性名，值为毫秒时间戳
+	PropertyRequestDeadline = "request-deadline"
+
+	// PropertyReplyError 响应错误信息属性名
+	PropertyReplyError = "reply-error"
+)
+
+// DefaultRequestTimeout 默认请求超时时间
+const DefaultRequestTimeout = 30 * time.Second
+
+// replySubscription 响应 Topic 的订阅名称
+const replySubscription = "pulbus-reply"
+
+var (
+	ErrRequestTimeout = errors.New("请求超时")
+	ErrReplyFailed    = errors.New("请求处理失败")
+)
+
+// WithRequestTimeout 设置 Request 的默认超时时间，context 未设置截止时间时生效，默认 30s
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(bus *Pulbus) {
+		bus.requestTimeout = timeout
+	}
+}
+
+// replies 当前实例的响应 Topic 监听，首次调用 Request 时创建
+type replies struct {
+	mu       sync.Mutex
+	topic    string
+	consumer pulsar.Consumer
+	closed   bool
+	done     chan struct{}
+
+	pending sync.Map // map[correlationID]chan pulsar.Message - 等待响应的请求
+}
+
+// listen 创建响应 Topic 的订阅并返回响应 Topic
+// 响应 Topic 使用 non-persistent，每个实例独占，实例退出后由 broker 自动回收
+func (bus *Pulbus) listen() (string, error) {
+	r := &bus.replies
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return "", ErrBusClosed
+	}
+
+	if r.consumer != nil {
+		return r.topic, nil
+	}
+
+	config := ParseTopic(bus.ResolveTopic("pulbus-reply-" + uuid.NewString()))
+	config.Domain = NonPersistentDomain
+	topic := config.FullName()
+
+	consumer, err := bus.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: replySubscription,
+		Type:             pulsar.Exclusive,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	r.topic = topic
+	r.consumer = consumer
+	r.done = make(chan struct{})
+	go r.run(consumer)
+
+	return topic, nil
+}
+
+// run 接收响应并分发给等待的请求，未找到请求的响应（已超时）直接丢弃
+func (r *replies) run(consumer pulsar.Consumer) {
+	for {
+		msg, err := consumer.Receive(context.Background())
+		if err != nil {
+			r.mu.Lock()
+			closed := r.closed
+			r.mu.Unlock()
+			if closed {
+				return
+			}
+
+			zap.L().Error("[Pulsar Request] 接收响应失败", zap.Error(err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		_ = consumer.Ack(msg)
+
+		id := msg.Properties()[PropertyCorrelationID]
+		if ch, ok := r.pending.LoadAndDelete(id); ok {
+			ch.(chan pulsar.Message) <- msg
+		} else {
+			zap.L().Warn("[Pulsar Request] 丢弃无等待请求的响应", zap.String("correlation_id", id))
+		}
+	}
+}
+
+// close 关闭响应 Topic 的订阅，等待中的请求返回 ErrBusClosed
+func (r *replies) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.closed = true
+
+	if r.consumer != nil {
+		close(r.done)
+		r.consumer.Close()
+	}
+}
+
+// Request 发送请求消息并等待响应，context 未设置截止时间时使用 WithRequestTimeout 设置的超时时间
+// 响应方处理失败时返回 ErrReplyFailed 和响应消息，超时返回 ErrRequestTimeout
+//
+// 使用示例:
+//
+//	reply, err := bus.Request(ctx, "order-query", []byte(`{"id":"1"}`))
+//	if err != nil {
+//	    return err
+//	}
+//	fmt.Println(string(reply.Payload()))
+func (bus *Pulbus) Request(ctx context.Context, topic string, payload []byte, messageOpts ...ProducerOption) (reply pulsar.Message, err error) {
+	var replyTo string
+	replyTo, err = bus.listen()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := bus.requestTimeout
+		if timeout <= 0 {
+			timeout = DefaultRequestTimeout
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+
+	id := uuid.NewString()
+	ch := make(chan pulsar.Message, 1)
+	bus.replies.pending.Store(id, ch)
+	defer bus.replies.pending.Delete(id)
+
+	err = bus.Send(ctx, topic, append(messageOpts,
+		WithPayload(payload),
+		withProperty(PropertyReplyTo, replyTo),
+		withProperty(PropertyCorrelationID, id),
+		withProperty(PropertyRequestDeadline, strconv.FormatInt(deadline.UnixMilli(), 10)),
+	)...)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply = <-ch:
+		if s, ok := reply.Properties()[PropertyReplyError]; ok {
+			return reply, fmt.Errorf("%w: %s", ErrReplyFailed, s)
+		}
+		return reply, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: topic=%s", ErrRequestTimeout, topic)
+		}
+		return nil, ctx.Err()
+	case <-bus.replies.done:
+		return nil, ErrBusClosed
+	}
+}
+
+// ReplyHandler 请求处理函数，返回的内容作为响应发送给请求方
+type ReplyHandler func(ctx context.Context, msg pulsar.Message) ([]byte, error)
+
+// Reply 消费请求消息并发送响应，阻塞直到 context 取消
+// handler 返回的错误作为响应错误发送给请求方，消息不会重投；响应发送失败时消息按处理失败重投
+// 已超过请求截止时间的消息直接 ack，不调用 handler；不含 PropertyReplyTo 的消息按普通消息处理
+//
+// 使用示例:
+//
+//	err := bus.Reply(ctx, "order-query", "order-service", func(ctx context.Context, msg pulsar.Message) ([]byte, error) {
+//	    return sonic.Marshal(order)
+//	})
+func (bus *Pulbus) Reply(ctx context.Context, topic, subscription string, handler ReplyHandler, opts ...ConsumerOption) error {
+	return bus.ConsumeContext(ctx, topic, subscription, func(ctx context.Context, msg pulsar.Message) error {
+		properties := msg.Properties()
+		replyTo := properties[PropertyReplyTo]
+		if replyTo == "" {
+			_, err := handler(ctx, msg)
+			return err
+		}
+
+		if s, ok := properties[PropertyRequestDeadline]; ok {
+			if ms, err := strconv.ParseInt(s, 10, 64); err == nil && time.Now().After(time.UnixMilli(ms)) {
+				zap.L().Warn("[Pulsar Reply] 跳过已超时的请求", messageFields(ctx, msg)...)
+				return nil
+			}
+		}
+
+		payload, err := handler(ctx, msg)
+		if payload == nil {
+			payload = []byte{}
+		}
+
+		replyOpts := []ProducerOption{
+			WithPayload(payload),
+			withProperty(PropertyCorrelationID, properties[PropertyCorrelationID]),
+		}
+		if err != nil {
+			replyOpts = append(replyOpts, withProperty(PropertyReplyError, err.Error()))
+		}
+
+		// 响应 Topic 随请求方实例变化，使用独立的 producer 缓存
+		return bus.sendReply(ctx, replyTo, replyOpts...)
+	}, opts...)
+}
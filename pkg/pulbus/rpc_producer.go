@@ -0,0 +1,214 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+const (
+	// DefaultReplyProducers 默认缓存的响应 producer 数量
+	DefaultReplyProducers = 64
+
+	// DefaultReplyProducerIdle 默认响应 producer 空闲关闭时间
+	DefaultReplyProducerIdle = 5 * time.Minute
+)
+
+// WithReplyProducers 设置 Reply 发送响应的 producer 缓存，默认缓存 64 个，空闲 5m 后关闭
+// 每个请求方实例使用独立的响应 Topic，请求方重启后旧的响应 Topic 不再使用，超过数量或空闲的 producer 会被关闭
+func WithReplyProducers(size int, idle time.Duration) Option {
+	return func(bus *Pulbus) {
+		bus.replyProducers.size = size
+		bus.replyProducers.idle = idle
+	}
+}
+
+// replyProducers Reply 使用的响应 producer 缓存，按最近使用顺序淘汰，不使用全局 producer 缓存
+type replyProducers struct {
+	mu     sync.Mutex
+	size   int
+	idle   time.Duration
+	items  map[string]*list.Element // map[Topic]*list.Element(*replyProducer)
+	order  list.List                // 最近使用的在前
+	closed bool
+}
+
+// replyProducer 缓存的响应 producer，引用计数归零且已淘汰时关闭
+type replyProducer struct {
+	topic    string
+	ready    chan struct{} // 创建完成后关闭
+	producer pulsar.Producer
+	err      error
+
+	refs     int
+	evicted  bool
+	lastUsed time.Time
+}
+
+// get 获取响应 Topic 的 producer 并增加引用计数，使用完成后需调用 put
+// 同一 Topic 并发获取时只创建一次
+func (c *replyProducers) get(client pulsar.Client, topic string) (*replyProducer, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrBusClosed
+	}
+	if c.items == nil {
+		c.items = make(map[string]*list.Element)
+	}
+
+	now := time.Now()
+	var p *replyProducer
+	if e, ok := c.items[topic]; ok {
+		p = e.Value.(*replyProducer)
+		c.order.MoveToFront(e)
+	} else {
+		p = &replyProducer{topic: topic, ready: make(chan struct{})}
+		c.items[topic] = c.order.PushFront(p)
+		go c.create(client, p)
+	}
+	p.refs++
+	p.lastUsed = now
+	closing := c.evict(now)
+	c.mu.Unlock()
+
+	for _, e := range closing {
+		e.close()
+	}
+
+	<-p.ready
+	if p.err != nil {
+		c.put(p)
+		return nil, p.err
+	}
+	return p, nil
+}
+
+// create 创建 producer，失败时从缓存中移除以便下次重新创建
+func (c *replyProducers) create(client pulsar.Client, p *replyProducer) {
+	p.producer, p.err = client.CreateProducer(pulsar.ProducerOptions{
+		Topic:           p.topic,
+		DisableBatching: true, // 响应需要尽快送达
+	})
+
+	if p.err != nil {
+		c.mu.Lock()
+		if e, ok := c.items[p.topic]; ok && e.Value == p {
+			c.remove(e)
+		}
+		c.mu.Unlock()
+	}
+	close(p.ready)
+}
+
+// put 减少引用计数，已淘汰的 producer 在引用计数归零时关闭
+func (c *replyProducers) put(p *replyProducer) {
+	c.mu.Lock()
+	p.refs--
+	closing := p.evicted && p.refs == 0
+	c.mu.Unlock()
+
+	if closing {
+		p.close()
+	}
+}
+
+// evict 淘汰超过数量和空闲时间的 producer，返回需要关闭的 producer，调用方需持有锁
+func (c *replyProducers) evict(now time.Time) (closing []*replyProducer) {
+	size := c.size
+	if size <= 0 {
+		size = DefaultReplyProducers
+	}
+	idle := c.idle
+	if idle <= 0 {
+		idle = DefaultReplyProducerIdle
+	}
+
+	for e := c.order.Back(); e != nil; {
+		p := e.Value.(*replyProducer)
+		prev := e.Prev()
+		if c.order.Len() <= size && now.Sub(p.lastUsed) < idle {
+			break
+		}
+
+		c.remove(e)
+		if p.refs == 0 {
+			closing = append(closing, p)
+		}
+		e = prev
+	}
+	return
+}
+
+// remove 从缓存中移除，调用方需持有锁
+func (c *replyProducers) remove(e *list.Element) {
+	p := e.Value.(*replyProducer)
+	p.evicted = true
+	c.order.Remove(e)
+	delete(c.items, p.topic)
+}
+
+// close 关闭所有 producer，使用中的 producer 在使用完成后关闭
+func (c *replyProducers) close() {
+	c.mu.Lock()
+	c.closed = true
+	var closing []*replyProducer
+	for e := c.order.Front(); e != nil; e = c.order.Front() {
+		p := e.Value.(*replyProducer)
+		c.remove(e)
+		if p.refs == 0 {
+			closing = append(closing, p)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, p := range closing {
+		p.close()
+	}
+}
+
+// len 返回缓存的 producer 数量
+func (c *replyProducers) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// close 等待创建完成后关闭 producer
+func (p *replyProducer) close() {
+	<-p.ready
+	if p.producer != nil {
+		p.producer.Close()
+	}
+}
+
+// sendReply 使用响应 producer 缓存发送响应
+func (bus *Pulbus) sendReply(ctx context.Context, topic string, messageOpts ...ProducerOption) error {
+	msg, err := buildMessage(messageOpts...)
+	if err != nil {
+		return err
+	}
+	bus.inject(ctx, msg)
+
+	topic = bus.ResolveTopic(topic)
+
+	var p *replyProducer
+	p, err = bus.replyProducers.get(bus.client, topic)
+	if err != nil {
+		return err
+	}
+	defer bus.replyProducers.put(p)
+
+	start := time.Now()
+	_, err = p.producer.Send(ctx, msg)
+	bus.metricsHook().ObservePublish(topic, time.Since(start), err)
+	return err
+}
@@ -0,0 +1,114 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestReply(t *testing.T) {
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bus.Reply(ctx, "echo", "echo-sub", func(_ context.Context, msg pulsar.Message) ([]byte, error) {
+			if string(msg.Payload()) == "invalid" {
+				return nil, errors.New("invalid request")
+			}
+			return append([]byte("echo: "), msg.Payload()...), nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	reply, err := bus.Request(ctx, "echo", []byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, "echo: hello", string(reply.Payload()))
+
+	_, err = bus.Request(ctx, "echo", []byte("invalid"))
+	require.ErrorIs(t, err, ErrReplyFailed)
+	require.ErrorContains(t, err, "invalid request")
+}
+
+func TestRequestTimeout(t *testing.T) {
+	bus := NewMemory(WithRequestTimeout(50 * time.Millisecond))
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	_, err := bus.Request(context.Background(), "nobody", []byte("hello"))
+	require.ErrorIs(t, err, ErrRequestTimeout)
+
+	// 超时后清理等待中的请求
+	var pending int
+	bus.replies.pending.Range(func(_, _ any) bool {
+		pending++
+		return true
+	})
+	require.Zero(t, pending)
+}
+
+func TestRequestClosed(t *testing.T) {
+	bus := NewMemory()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := bus.Request(context.Background(), "nobody", []byte("hello"))
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		var pending bool
+		bus.replies.pending.Range(func(_, _ any) bool {
+			pending = true
+			return false
+		})
+		return pending
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, bus.Close())
+	require.ErrorIs(t, <-done, ErrBusClosed)
+
+	_, err := bus.Request(context.Background(), "nobody", []byte("hello"))
+	require.ErrorIs(t, err, ErrBusClosed)
+}
+
+func TestReplyProducers(t *testing.T) {
+	bus := NewMemory(WithReplyProducers(2, 50*time.Millisecond))
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	ctx := context.Background()
+	for _, topic := range []string{"reply-1", "reply-2", "reply-3"} {
+		require.NoError(t, bus.sendReply(ctx, topic, WithPayload([]byte("ok"))))
+	}
+
+	// 超过数量后淘汰最久未使用的 producer，且不进入全局 producer 缓存
+	require.Equal(t, 2, bus.replyProducers.len())
+	var producers int
+	bus.producers.Range(func(_, _ any) bool {
+		producers++
+		return true
+	})
+	require.Zero(t, producers)
+
+	// 空闲超时后淘汰
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, bus.sendReply(ctx, "reply-4", WithPayload([]byte("ok"))))
+	require.Equal(t, 1, bus.replyProducers.len())
+
+	require.NoError(t, bus.Close())
+	require.Zero(t, bus.replyProducers.len())
+	require.ErrorIs(t, bus.sendReply(ctx, "reply-5", WithPayload([]byte("ok"))), ErrBusClosed)
+}
@@ -0,0 +1,333 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+// Package scheduler 基于 Pulsar 延迟投递的任务调度
+//
+// 任务按名称注册，使用 At / After 调度，到期后由消费者执行；
+// 任务 ID 同时作为去重和取消的墓碑 key，已执行的任务不会重复执行，取消后的任务不会执行。
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"nexis.run/nexa/pkg/pulbus"
+)
+
+const (
+	// PropertyJobName 任务名称属性名
+	PropertyJobName = "job-name"
+
+	// PropertyJobID 任务 ID 属性名
+	PropertyJobID = "job-id"
+)
+
+const (
+	// DefaultTopic 默认任务 Topic
+	DefaultTopic = "scheduled-jobs"
+
+	// DefaultSubscription 默认任务订阅名称
+	DefaultSubscription = "scheduler"
+
+	// DefaultTombstoneTTL 默认墓碑保留时间，需大于任务的最长调度延迟
+	DefaultTombstoneTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrJobNotRegistered  = errors.New("任务未注册")
+	ErrJobAlreadyExists  = errors.New("任务已注册")
+	ErrJobNotCancellable = errors.New("任务已执行或已取消")
+)
+
+// RetryPolicy 任务失败重试策略
+type RetryPolicy struct {
+	MaxAttempts uint32        // 最大执行次数，超过后进入死信 Topic，0 表示不限制
+	MinBackoff  time.Duration // 首次重试延迟
+	MaxBackoff  time.Duration // 最大重试延迟
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	MinBackoff:  time.Second,
+	MaxBackoff:  5 * time.Minute,
+}
+
+// Scheduler 任务调度器
+type Scheduler struct {
+	bus   pulbus.Bus
+	store pulbus.DedupeStore
+	codec pulbus.Codec
+
+	topic        string
+	subscription string
+	tombstoneTTL time.Duration
+	lease        time.Duration
+	retry        RetryPolicy
+	consumerOpts []pulbus.ConsumerOption
+
+	mu       sync.RWMutex
+	handlers map[string]func(ctx context.Context, msg pulsar.Message) error
+}
+
+// Option 调度器配置选项
+type Option func(*Scheduler)
+
+// WithStore 设置墓碑存储，多实例部署时必须使用共享存储，例如 pulbus.NewSQLDedupeStore
+// 未设置时使用内存存储，取消操作仅对当前实例生效且重启后丢失，仅适用于单实例部署和测试
+func WithStore(store pulbus.DedupeStore) Option {
+	return func(s *Scheduler) {
+		s.store = store
+	}
+}
+
+// WithCodec 设置任务参数编解码器，默认 pulbus.JSONCodec
+func WithCodec(codec pulbus.Codec) Option {
+	return func(s *Scheduler) {
+		s.codec = codec
+	}
+}
+
+// WithTopic 设置任务 Topic 和订阅名称
+func WithTopic(topic, subscription string) Option {
+	return func(s *Scheduler) {
+		s.topic = topic
+		s.subscription = subscription
+	}
+}
+
+// WithTombstoneTTL 设置墓碑保留时间，默认 7 天
+func WithTombstoneTTL(ttl time.Duration) Option {
+	return func(s *Scheduler) {
+		s.tombstoneTTL = ttl
+	}
+}
+
+// WithLease 设置任务执行租约时间，默认 pulbus.DefaultDedupeLease
+// 执行中的任务在租约内不会被重复执行，进程崩溃时租约到期后任务重新执行；任务的 context 在租约到期时取消
+func WithLease(lease time.Duration) Option {
+	return func(s *Scheduler) {
+		s.lease = lease
+	}
+}
+
+// WithRetryPolicy 设置任务失败重试策略，默认 DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(s *Scheduler) {
+		s.retry = policy
+	}
+}
+
+// WithConsumerOptions 设置任务消费者的额外选项
+func WithConsumerOptions(opts ...pulbus.ConsumerOption) Option {
+	return func(s *Scheduler) {
+		s.consumerOpts = append(s.consumerOpts, opts...)
+	}
+}
+
+// New 创建任务调度器
+//
+// 使用示例:
+//
+//	s := scheduler.New(bus, scheduler.WithStore(pulbus.NewSQLDedupeStore(drv)))
+//	timeout := scheduler.Register(s, "order-timeout", func(ctx context.Context, orderID string) error {
+//	    return closeOrder(ctx, orderID)
+//	})
+//	go s.Run(ctx)
+//
+//	id, err := timeout.After(ctx, 30*time.Minute, order.ID, scheduler.WithJobID("order-timeout:"+order.ID))
+//	err = s.Cancel(ctx, id)
+func New(bus pulbus.Bus, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		bus:          bus,
+		codec:        pulbus.JSONCodec{},
+		topic:        DefaultTopic,
+		subscription: DefaultSubscription,
+		tombstoneTTL: DefaultTombstoneTTL,
+		lease:        pulbus.DefaultDedupeLease,
+		retry:        DefaultRetryPolicy,
+		handlers:     make(map[string]func(ctx context.Context, msg pulsar.Message) error),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.store == nil {
+		zap.L().Warn("[Scheduler] 未设置共享墓碑存储，使用内存存储: 取消操作仅对当前实例生效且重启后丢失，多实例部署时需使用 WithStore 设置共享存储",
+			zap.String("topic", s.topic),
+			zap.String("subscription", s.subscription),
+		)
+		s.store = pulbus.NewMemoryDedupeStore()
+	}
+
+	return s
+}
+
+// Handler 任务处理函数，返回错误时按重试策略重试
+type Handler[T any] func(ctx context.Context, payload T) error
+
+// Job 已注册的任务
+type Job[T any] struct {
+	scheduler *Scheduler
+	name      string
+}
+
+// Register 注册任务，同名任务重复注册时 panic
+func Register[T any](s *Scheduler, name string, handler Handler[T]) *Job[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.handlers[name]; ok {
+		panic(fmt.Errorf("%w: %s", ErrJobAlreadyExists, name))
+	}
+
+	s.handlers[name] = func(ctx context.Context, msg pulsar.Message) error {
+		payload, err := pulbus.Decode[T](s.codec, msg)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, payload)
+	}
+
+	return &Job[T]{scheduler: s, name: name}
+}
+
+// Name 返回任务名称
+func (j *Job[T]) Name() string {
+	return j.name
+}
+
+// ScheduleOptions 任务调度配置
+type ScheduleOptions struct {
+	id string
+}
+
+// ScheduleOption 任务调度选项
+type ScheduleOption func(*ScheduleOptions)
+
+// WithJobID 设置任务 ID，默认随机生成
+// 使用业务 ID 作为任务 ID 时可以直接取消任务，无需保存 At / After 返回的 ID
+func WithJobID(id string) ScheduleOption {
+	return func(o *ScheduleOptions) {
+		o.id = id
+	}
+}
+
+// At 在指定时间执行任务，返回任务 ID
+func (j *Job[T]) At(ctx context.Context, t time.Time, payload T, opts ...ScheduleOption) (string, error) {
+	return j.schedule(ctx, payload, pulbus.WithDeliverAt(t), opts...)
+}
+
+// After 在指定延迟后执行任务，返回任务 ID
+func (j *Job[T]) After(ctx context.Context, d time.Duration, payload T, opts ...ScheduleOption) (string, error) {
+	return j.schedule(ctx, payload, pulbus.WithProducerDeliverAfter(d), opts...)
+}
+
+// schedule 编码任务参数并发送延迟消息
+func (j *Job[T]) schedule(ctx context.Context, payload T, deliver pulbus.ProducerOption, opts ...ScheduleOption) (string, error) {
+	options := &ScheduleOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.id == "" {
+		options.id = uuid.NewString()
+	}
+
+	s := j.scheduler
+	b, err := s.codec.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.bus.Send(ctx, s.topic,
+		deliver,
+		pulbus.WithPayload(b),
+		pulbus.WithProducerKey(options.id),
+		pulbus.WithProperties(map[string]string{
+			pulbus.PropertyContentType: s.codec.ContentType(),
+			PropertyJobName:            j.name,
+			PropertyJobID:              options.id,
+		}),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return options.id, nil
+}
+
+// tombstoneKey 任务的墓碑 key
+func (s *Scheduler) tombstoneKey(id string) string {
+	return s.topic + ":" + s.subscription + ":" + id
+}
+
+// Cancel 取消任务，任务已开始执行、已执行或已取消时返回 ErrJobNotCancellable
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	key := s.tombstoneKey(id)
+	acquired, err := s.store.Acquire(ctx, key, s.tombstoneTTL)
+	if err != nil {
+		return err
+	}
+
+	if !acquired {
+		return fmt.Errorf("%w: %s", ErrJobNotCancellable, id)
+	}
+
+	// 标记为已完成，到期的任务直接 ack
+	return s.store.Complete(ctx, key, s.tombstoneTTL)
+}
+
+// Run 消费并执行到期的任务，阻塞直到 context 取消
+// 订阅类型为 KeyShared，同一任务 ID 的消息由同一消费者处理
+func (s *Scheduler) Run(ctx context.Context) error {
+	opts := []pulbus.ConsumerOption{
+		pulbus.WithConsumerSubscriptionType(pulsar.KeyShared),
+		pulbus.WithConsumerMaxRedeliveries(s.retry.MaxAttempts),
+	}
+	if s.retry.MinBackoff > 0 {
+		opts = append(opts, pulbus.WithConsumerNackBackoff(s.retry.MinBackoff, s.retry.MaxBackoff))
+	}
+
+	return s.bus.ConsumeContext(ctx, s.topic, s.subscription, s.handler(), append(opts, s.consumerOpts...)...)
+}
+
+// handler 返回任务消费处理函数，使用幂等中间件以任务墓碑去重
+// 执行前以租约占用墓碑，执行成功后标记为已完成；租约期间重复的任务消息返回 pulbus.ErrDedupeInProgress 并重投；
+// 执行失败时释放墓碑以便重试，重试前取消的任务不再执行
+func (s *Scheduler) handler() pulbus.ContextHandler {
+	return pulbus.IdempotencyMiddleware(s.store,
+		pulbus.WithDedupeKey(func(msg pulsar.Message) string {
+			id := msg.Properties()[PropertyJobID]
+			if id == "" {
+				return ""
+			}
+			return s.tombstoneKey(id)
+		}),
+		pulbus.WithDedupeUnscoped(),
+		pulbus.WithDedupeLease(s.lease),
+		pulbus.WithDedupeTTL(s.tombstoneTTL),
+	)(s.dispatch)
+}
+
+// dispatch 按任务名称执行任务
+func (s *Scheduler) dispatch(ctx context.Context, msg pulsar.Message) error {
+	name := msg.Properties()[PropertyJobName]
+
+	s.mu.RLock()
+	handler, ok := s.handlers[name]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotRegistered, name)
+	}
+
+	return handler(ctx, msg)
+}
@@ -0,0 +1,178 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"nexis.run/nexa/pkg/pulbus"
+)
+
+type orderTimeout struct {
+	OrderID string `json:"order_id"`
+}
+
+func TestScheduler(t *testing.T) {
+	bus := pulbus.NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	s := New(bus,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		WithConsumerOptions(pulbus.WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest)),
+	)
+
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int)
+		executed = make(map[string]time.Time)
+	)
+	job := Register(s, "order-timeout", func(_ context.Context, payload orderTimeout) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// 首次执行失败后重试
+		attempts[payload.OrderID]++
+		if payload.OrderID == "retry" && attempts[payload.OrderID] == 1 {
+			return errors.New("retry")
+		}
+		executed[payload.OrderID] = time.Now()
+		return nil
+	})
+
+	require.Panics(t, func() {
+		Register(s, "order-timeout", func(context.Context, orderTimeout) error { return nil })
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	start := time.Now()
+	_, err := job.After(ctx, 100*time.Millisecond, orderTimeout{OrderID: "1"})
+	require.NoError(t, err)
+	_, err = job.At(ctx, time.Now().Add(50*time.Millisecond), orderTimeout{OrderID: "retry"})
+	require.NoError(t, err)
+
+	// 执行前取消
+	id, err := job.After(ctx, 100*time.Millisecond, orderTimeout{OrderID: "cancelled"}, WithJobID("order-timeout:cancelled"))
+	require.NoError(t, err)
+	require.Equal(t, "order-timeout:cancelled", id)
+	require.NoError(t, s.Cancel(ctx, id))
+	require.ErrorIs(t, s.Cancel(ctx, id), ErrJobNotCancellable)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(executed) == 2
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// 已执行的任务不能取消
+	id, err = job.After(ctx, 0, orderTimeout{OrderID: "2"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(executed) == 3
+	}, time.Second, 5*time.Millisecond)
+	require.ErrorIs(t, s.Cancel(ctx, id), ErrJobNotCancellable)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	require.GreaterOrEqual(t, executed["1"].Sub(start), 100*time.Millisecond)
+	require.Equal(t, 2, attempts["retry"])
+	require.NotContains(t, attempts, "cancelled")
+}
+
+// stubMessage 仅用于测试任务执行的消息
+type stubMessage struct {
+	pulsar.Message
+
+	properties map[string]string
+	payload    []byte
+}
+
+func (m *stubMessage) ID() pulsar.MessageID {
+	return nil
+}
+
+func (m *stubMessage) Topic() string {
+	return DefaultTopic
+}
+
+func (m *stubMessage) Key() string {
+	return m.properties[PropertyJobID]
+}
+
+func (m *stubMessage) RedeliveryCount() uint32 {
+	return 0
+}
+
+func (m *stubMessage) Properties() map[string]string {
+	return m.properties
+}
+
+func (m *stubMessage) Payload() []byte {
+	return m.payload
+}
+
+func TestSchedulerLease(t *testing.T) {
+	s := New(pulbus.NewMemory(), WithStore(pulbus.NewMemoryDedupeStore()), WithLease(50*time.Millisecond))
+
+	var (
+		calls   atomic.Int32
+		block   = make(chan struct{})
+		started = make(chan struct{})
+	)
+	defer close(block)
+
+	Register(s, "order-timeout", func(context.Context, orderTimeout) error {
+		// 首次执行模拟进程崩溃，任务不返回也不响应 context
+		if calls.Add(1) == 1 {
+			close(started)
+			<-block
+		}
+		return nil
+	})
+
+	msg := &stubMessage{
+		properties: map[string]string{
+			pulbus.PropertyContentType: pulbus.ContentTypeJSON,
+			PropertyJobName:            "order-timeout",
+			PropertyJobID:              "1",
+		},
+		payload: []byte(`{"order_id":"1"}`),
+	}
+	ctx := context.Background()
+	handle := s.handler()
+
+	go func() {
+		_ = handle(ctx, msg)
+	}()
+	<-started
+
+	// 执行中的任务重投而不是 ack，且不能取消
+	require.ErrorIs(t, handle(ctx, msg), pulbus.ErrDedupeInProgress)
+	require.ErrorIs(t, s.Cancel(ctx, "1"), ErrJobNotCancellable)
+
+	// 租约到期后重投的任务重新执行，执行完成后不再执行
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, handle(ctx, msg))
+	require.NoError(t, handle(ctx, msg))
+	require.Equal(t, int32(2), calls.Load())
+	require.ErrorIs(t, s.Cancel(ctx, "1"), ErrJobNotCancellable)
+}
@@ -0,0 +1,354 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/bytedance/sonic"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrUnsupportedSchemaType = errors.New("不支持的 Schema 类型")
+	ErrSchemaIncompatible    = errors.New("Schema 不兼容")
+)
+
+// schemaTag 生成 Schema 时读取字段名的 tag
+const (
+	schemaTagJSON = "json"
+	schemaTagAvro = "avro"
+)
+
+// NewJSONSchema 根据 Go 结构体生成 JSON Schema，字段名读取 json tag
+//
+// 使用示例:
+//
+//	schema, err := NewJSONSchema[Order](nil)
+func NewJSONSchema[T any](properties map[string]string) (pulsar.Schema, error) {
+	def, err := avroSchemaOf(reflect.TypeFor[T](), schemaTagJSON)
+	if err != nil {
+		return nil, err
+	}
+	return pulsar.NewJSONSchemaWithValidation(def, properties)
+}
+
+// NewAvroSchema 根据 Go 结构体生成 Avro Schema，字段名读取 avro tag，与 Avro 编码时的字段映射一致
+func NewAvroSchema[T any](properties map[string]string) (pulsar.Schema, error) {
+	def, err := avroSchemaOf(reflect.TypeFor[T](), schemaTagAvro)
+	if err != nil {
+		return nil, err
+	}
+	return pulsar.NewAvroSchemaWithValidation(def, properties)
+}
+
+// NewProtoSchema 根据 protobuf 消息生成 Protobuf Native Schema
+//
+// 使用示例:
+//
+//	schema := NewProtoSchema[*pb.Order](nil)
+func NewProtoSchema[T proto.Message](properties map[string]string) pulsar.Schema {
+	var message T
+	return pulsar.NewProtoNativeSchemaWithMessage(message, properties)
+}
+
+// avroSchemaOf 根据 Go 类型生成 Avro record schema 定义
+// tag 为 json 时 time.Time 按 RFC3339 字符串描述，为 avro 时按 timestamp-millis 描述
+func avroSchemaOf(t reflect.Type, tag string) (string, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("%w: %s 不是结构体", ErrUnsupportedSchemaType, t)
+	}
+
+	g := &avroGenerator{tag: tag, defined: make(map[reflect.Type]bool)}
+	schema, err := g.typeOf(t)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := sonic.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// avroGenerator Avro schema 生成器
+type avroGenerator struct {
+	tag     string
+	defined map[reflect.Type]bool // 已定义的 record，再次出现时按名称引用
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// typeOf 返回类型对应的 Avro 类型
+func (g *avroGenerator) typeOf(t reflect.Type) (any, error) {
+	switch t {
+	case timeType:
+		if g.tag == schemaTagJSON {
+			return "string", nil
+		}
+		return map[string]any{"type": "long", "logicalType": "timestamp-millis"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "int", nil
+	case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return "long", nil
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
+		return "double", nil
+	case reflect.String:
+		return "string", nil
+	case reflect.Pointer:
+		inner, err := g.typeOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return []any{"null", inner}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes", nil
+		}
+		items, err := g.typeOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: map 的 key 必须为 string: %s", ErrUnsupportedSchemaType, t)
+		}
+		values, err := g.typeOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "map", "values": values}, nil
+	case reflect.Struct:
+		return g.record(t)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSchemaType, t)
+	}
+}
+
+// record 返回结构体对应的 Avro record
+func (g *avroGenerator) record(t reflect.Type) (any, error) {
+	if t.Name() == "" {
+		return nil, fmt.Errorf("%w: 不支持匿名结构体", ErrUnsupportedSchemaType)
+	}
+	if g.defined[t] {
+		return t.Name(), nil
+	}
+	g.defined[t] = true
+
+	fields, err := g.fields(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"type":   "record",
+		"name":   t.Name(),
+		"fields": fields,
+	}, nil
+}
+
+// fields 返回结构体的 Avro 字段，嵌入的结构体字段展开到当前 record
+func (g *avroGenerator) fields(t reflect.Type) ([]any, error) {
+	var fields []any
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get(g.tag), ",")
+		if name == "-" {
+			continue
+		}
+
+		// 与 encoding/json 一致，未导出的嵌入结构体的导出字段同样展开
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded, err := g.fields(f.Type)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, embedded...)
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		typ, err := g.typeOf(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+
+		field := map[string]any{"name": name, "type": typ}
+		if f.Type.Kind() == reflect.Pointer {
+			field["default"] = nil
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// schemaTypeName 返回 Admin 接口使用的 Schema 类型名称
+func schemaTypeName(t pulsar.SchemaType) (string, error) {
+	switch t {
+	case pulsar.JSON:
+		return "JSON", nil
+	case pulsar.AVRO:
+		return "AVRO", nil
+	case pulsar.PROTOBUF:
+		return "PROTOBUF", nil
+	case pulsar.ProtoNative:
+		return "PROTOBUF_NATIVE", nil
+	case pulsar.STRING:
+		return "STRING", nil
+	case pulsar.BYTES:
+		return "BYTES", nil
+	default:
+		return "", fmt.Errorf("%w: %d", ErrUnsupportedSchemaType, t)
+	}
+}
+
+// schemaPayload 将 pulsar.Schema 转换为 Admin 接口的 Schema 参数
+func schemaPayload(schema pulsar.Schema) (payload utils.PostSchemaPayload, err error) {
+	info := schema.GetSchemaInfo()
+	payload.SchemaType, err = schemaTypeName(info.Type)
+	if err != nil {
+		return
+	}
+	payload.Schema = info.Schema
+	payload.Properties = info.Properties
+	return
+}
+
+// GetSchema 获取 Topic 最新的 Schema 及版本
+func (admin *Admin) GetSchema(topic string) (*utils.SchemaInfoWithVersion, error) {
+	return admin.Schemas().GetSchemaInfoWithVersion(topic)
+}
+
+// UploadSchema 上传 Topic 的 Schema，与已有 Schema 不兼容时 broker 返回错误
+func (admin *Admin) UploadSchema(topic string, schema pulsar.Schema) error {
+	payload, err := schemaPayload(schema)
+	if err != nil {
+		return err
+	}
+	return admin.Schemas().CreateSchemaByPayload(topic, payload)
+}
+
+// CheckSchemaCompatibility 按 namespace 的兼容策略检查 Schema 是否兼容，不兼容时返回 ErrSchemaIncompatible
+// 可在发布前检查 Schema 演进是否会导致 producer / consumer 创建失败
+func (admin *Admin) CheckSchemaCompatibility(topic string, schema pulsar.Schema) error {
+	payload, err := schemaPayload(schema)
+	if err != nil {
+		return err
+	}
+
+	result, err := admin.Schemas().TestCompatibilityWithPostSchemaPayload(topic, payload)
+	if err != nil {
+		return err
+	}
+
+	if !result.IsCompatibility {
+		return fmt.Errorf("%w: topic=%s, strategy=%s", ErrSchemaIncompatible, topic, result.SchemaCompatibilityStrategy)
+	}
+	return nil
+}
+
+// WithConsumerSchema 设置订阅的 Schema，与 Topic 的 Schema 不兼容时创建 consumer 失败
+func WithConsumerSchema(schema pulsar.Schema) ConsumerOption {
+	return func(o *ConsumerOptions) {
+		o.schema = schema
+	}
+}
+
+// SchemaProducer 使用 Schema 编码的泛型消息生产者
+// Topic 的 producer 在创建时注册 Schema，Schema 不兼容时 NewSchemaProducer 直接返回错误
+type SchemaProducer[T any] struct {
+	bus   Bus
+	topic string
+}
+
+// NewSchemaProducer 创建使用 Schema 编码的泛型消息生产者
+// bus 为 *Pulbus（包括 NewMemory 创建的内存总线）时立即创建 producer 以检查 Schema 兼容性，
+// 同一 Topic 只能使用一种 Schema，已使用其他配置创建 producer 时返回 ErrProducerOptionsConflict
+// 其他 Bus 实现不检查 Schema，消息通过 ProducerMessage.Value 传递
+//
+// 使用示例:
+//
+//	schema, err := NewAvroSchema[Order](nil)
+//	producer, err := NewSchemaProducer[Order](bus, "orders", schema)
+//	err = producer.Send(ctx, order, WithProducerKey(order.ID))
+func NewSchemaProducer[T any](bus Bus, topic string, schema pulsar.Schema) (*SchemaProducer[T], error) {
+	if b, ok := bus.(*Pulbus); ok {
+		topic = b.ResolveTopic(topic)
+		if err := b.configureSchema(topic, schema); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SchemaProducer[T]{bus: bus, topic: topic}, nil
+}
+
+// configureSchema 为 Topic 的 producer 设置 Schema 并立即创建 producer
+func (bus *Pulbus) configureSchema(topic string, schema pulsar.Schema) error {
+	var cfg ProducerConfig
+	if c, ok := bus.producerConfigs.Load(topic); ok {
+		cfg = c.(ProducerConfig)
+	}
+	cfg.Schema = schema
+
+	err := bus.ConfigureProducer(topic, cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = bus.getProducer(topic)
+	return err
+}
+
+// Send 使用 Schema 编码并发送消息
+func (p *SchemaProducer[T]) Send(ctx context.Context, v T, messageOpts ...ProducerOption) error {
+	return p.bus.Send(ctx, p.topic, append(messageOpts, func(message *pulsar.ProducerMessage) {
+		message.Value = v
+	})...)
+}
+
+// SubscribeSchema 使用 Schema 解码并消费消息，Schema 不兼容时创建 consumer 失败并直接返回错误
+// 解码失败的消息不会进入 handler，而是交由 WithDecodeErrorHandler 处理
+//
+// 使用示例:
+//
+//	err := SubscribeSchema(ctx, bus, "orders", "order-sub", schema, func(msg pulsar.Message, order Order) error {
+//	    return nil
+//	})
+func SubscribeSchema[T any](ctx context.Context, bus Bus, topic, subscription string, schema pulsar.Schema, handler TypedHandler[T], opts ...ConsumerOption) error {
+	return bus.Consume(ctx, topic, subscription, func(msg pulsar.Message) error {
+		var v T
+		if err := msg.GetSchemaValue(newTarget(&v)); err != nil {
+			contentType, _ := schemaTypeName(schema.GetSchemaInfo().Type)
+			return &DecodeError{ContentType: contentType, Err: err}
+		}
+		return handler(msg, v)
+	}, append(opts, WithConsumerSchema(schema))...)
+}
@@ -0,0 +1,184 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/admin"
+	"github.com/apache/pulsar-client-go/pulsaradmin/pkg/utils"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type schemaAudit struct {
+	CreatedBy string `json:"created_by" avro:"created_by"`
+}
+
+type schemaItem struct {
+	SKU string `json:"sku" avro:"sku"`
+	Qty int32  `json:"qty" avro:"qty"`
+}
+
+type schemaOrder struct {
+	schemaAudit
+
+	ID        string            `json:"id" avro:"id"`
+	Amount    float64           `json:"amount" avro:"amount"`
+	Paid      bool              `json:"paid" avro:"paid"`
+	Items     []schemaItem      `json:"items" avro:"items"`
+	Labels    map[string]string `json:"labels" avro:"labels"`
+	Remark    *string           `json:"remark" avro:"remark"`
+	CreatedAt time.Time         `json:"created_at" avro:"created_at"`
+	Internal  string            `json:"-" avro:"-"`
+}
+
+func TestAvroSchemaOf(t *testing.T) {
+	def, err := avroSchemaOf(reflect.TypeFor[schemaOrder](), schemaTagAvro)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"type": "record",
+		"name": "schemaOrder",
+		"fields": [
+			{"name": "created_by", "type": "string"},
+			{"name": "id", "type": "string"},
+			{"name": "amount", "type": "double"},
+			{"name": "paid", "type": "boolean"},
+			{"name": "items", "type": {"type": "array", "items": {"type": "record", "name": "schemaItem", "fields": [
+				{"name": "sku", "type": "string"},
+				{"name": "qty", "type": "int"}
+			]}}},
+			{"name": "labels", "type": {"type": "map", "values": "string"}},
+			{"name": "remark", "type": ["null", "string"], "default": null},
+			{"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+		]
+	}`, def)
+
+	// JSON Schema 中时间按字符串描述
+	def, err = avroSchemaOf(reflect.TypeFor[*schemaOrder](), schemaTagJSON)
+	require.NoError(t, err)
+	require.Contains(t, def, `"name":"created_at","type":"string"`)
+
+	_, err = avroSchemaOf(reflect.TypeFor[struct{ C chan int }](), schemaTagJSON)
+	require.ErrorIs(t, err, ErrUnsupportedSchemaType)
+	_, err = avroSchemaOf(reflect.TypeFor[string](), schemaTagJSON)
+	require.ErrorIs(t, err, ErrUnsupportedSchemaType)
+}
+
+func TestSchemas(t *testing.T) {
+	remark := "urgent"
+	order := schemaOrder{
+		schemaAudit: schemaAudit{CreatedBy: "nexa"},
+		ID:          "1",
+		Amount:      9.9,
+		Items:       []schemaItem{{SKU: "A", Qty: 2}},
+		Labels:      map[string]string{"channel": "app"},
+		Remark:      &remark,
+		CreatedAt:   time.UnixMilli(time.Now().UnixMilli()).UTC(),
+	}
+
+	avro, err := NewAvroSchema[schemaOrder](nil)
+	require.NoError(t, err)
+	require.Equal(t, pulsar.AVRO, avro.GetSchemaInfo().Type)
+
+	jsonSchema, err := NewJSONSchema[schemaOrder](nil)
+	require.NoError(t, err)
+	require.Equal(t, pulsar.JSON, jsonSchema.GetSchemaInfo().Type)
+
+	for _, schema := range []pulsar.Schema{avro, jsonSchema} {
+		b, err := schema.Encode(order)
+		require.NoError(t, err)
+
+		var v schemaOrder
+		require.NoError(t, schema.Decode(b, &v))
+		require.Equal(t, order, v)
+	}
+
+	proto := NewProtoSchema[*wrapperspb.StringValue](nil)
+	require.Equal(t, pulsar.ProtoNative, proto.GetSchemaInfo().Type)
+}
+
+func TestSchemaProducerMemory(t *testing.T) {
+	var bus Bus = NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	schema, err := NewAvroSchema[schemaItem](nil)
+	require.NoError(t, err)
+
+	producer, err := NewSchemaProducer[schemaItem](bus, "items", schema)
+	require.NoError(t, err)
+
+	// 同一 Topic 使用其他 Schema
+	other, err := NewJSONSchema[schemaItem](nil)
+	require.NoError(t, err)
+	_, err = NewSchemaProducer[schemaItem](bus, "items", other)
+	require.ErrorIs(t, err, ErrProducerOptionsConflict)
+
+	received := make(chan schemaItem, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = SubscribeSchema(ctx, bus, "items", "item-sub", schema, func(_ pulsar.Message, v schemaItem) error {
+			received <- v
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest))
+	}()
+
+	require.NoError(t, producer.Send(ctx, schemaItem{SKU: "A", Qty: 2}))
+	require.Equal(t, schemaItem{SKU: "A", Qty: 2}, <-received)
+}
+
+// schemaAdmin 记录 Schema 请求的 Pulsar Admin
+type schemaAdmin struct {
+	admin.Client
+
+	payloads   map[string]utils.PostSchemaPayload
+	compatible bool
+}
+
+func (a *schemaAdmin) Schemas() admin.Schema { return stubSchemas{a: a} }
+
+type stubSchemas struct {
+	admin.Schema
+	a *schemaAdmin
+}
+
+func (s stubSchemas) CreateSchemaByPayload(topic string, payload utils.PostSchemaPayload) error {
+	s.a.payloads[topic] = payload
+	return nil
+}
+
+func (s stubSchemas) TestCompatibilityWithPostSchemaPayload(string, utils.PostSchemaPayload) (*utils.IsCompatibility, error) {
+	return &utils.IsCompatibility{
+		IsCompatibility:             s.a.compatible,
+		SchemaCompatibilityStrategy: utils.SchemaCompatibilityStrategyFull,
+	}, nil
+}
+
+func TestAdminSchema(t *testing.T) {
+	stub := &schemaAdmin{payloads: make(map[string]utils.PostSchemaPayload)}
+	a := &Admin{Client: stub}
+
+	schema, err := NewAvroSchema[schemaItem](map[string]string{"owner": "order"})
+	require.NoError(t, err)
+
+	require.NoError(t, a.UploadSchema("orders", schema))
+	payload := stub.payloads["orders"]
+	require.Equal(t, "AVRO", payload.SchemaType)
+	require.Equal(t, schema.GetSchemaInfo().Schema, payload.Schema)
+	require.Equal(t, map[string]string{"owner": "order"}, payload.Properties)
+
+	require.ErrorIs(t, a.CheckSchemaCompatibility("orders", schema), ErrSchemaIncompatible)
+	stub.compatible = true
+	require.NoError(t, a.CheckSchemaCompatibility("orders", schema))
+
+	require.ErrorIs(t, a.UploadSchema("orders", pulsar.NewInt32Schema(nil)), ErrUnsupportedSchemaType)
+}
@@ -6,7 +6,11 @@ package pulbus
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+
+	"nexis.run/nexa/kit"
 )
 
 // 常用的 Namespace 配置
@@ -15,17 +19,51 @@ const (
 	DefaultNamespace = "public/default"
 
 	// ProductionNamespace 生产环境 namespace
+	//
+	// Deprecated: 环境作为 namespace 而不是 tenant，请使用 EnvironmentTenantNamespace(tenant, kit.Production)
 	ProductionNamespace = "production/app"
 
 	// DevelopmentNamespace 开发环境 namespace
+	//
+	// Deprecated: 环境作为 namespace 而不是 tenant，请使用 EnvironmentTenantNamespace(tenant, kit.Development)
 	DevelopmentNamespace = "development/app"
 
 	// TestNamespace 测试环境 namespace
+	//
+	// Deprecated: 环境作为 namespace 而不是 tenant，请使用 EnvironmentTenantNamespace(tenant, kit.Staging)
 	TestNamespace = "test/app"
 )
 
+// Topic 持久化类型
+const (
+	PersistentDomain    = "persistent"
+	NonPersistentDomain = "non-persistent"
+)
+
+// PartitionSuffix 分区 Topic 后缀
+const PartitionSuffix = "-partition-"
+
+// EnvironmentNamespace 返回环境对应的 namespace 名称（不含 tenant）
+// 例如: kit.Staging -> staging
+func EnvironmentNamespace(env kit.Environment) string {
+	return string(env)
+}
+
+// EnvironmentTenantNamespace 返回租户下环境对应的 namespace 完整路径，与 WithEnvironment 解析的 Topic 一致
+// 例如: ("nexa", kit.Staging) -> nexa/staging
+func EnvironmentTenantNamespace(tenant string, env kit.Environment) string {
+	return NewTopicBuilder(tenant, EnvironmentNamespace(env)).Namespace()
+}
+
+// 死信和重试 Topic 后缀
+const (
+	DeadLetterSuffix  = "-DLQ"
+	RetryLetterSuffix = "-RETRY"
+)
+
 // TopicConfig Topic 配置
 type TopicConfig struct {
+	Domain    string // 持久化类型，默认 "persistent"
 	Tenant    string // 租户，默认 "public"
 	Namespace string // 命名空间，默认 "default"
 	Topic     string // Topic 名称
@@ -35,6 +73,7 @@ type TopicConfig struct {
 // DefaultTopicConfig 返回默认的 Topic 配置
 func DefaultTopicConfig(topic string) TopicConfig {
 	return TopicConfig{
+		Domain:    PersistentDomain,
 		Tenant:    "public",
 		Namespace: "default",
 		Topic:     topic,
@@ -45,12 +84,11 @@ func DefaultTopicConfig(topic string) TopicConfig {
 // FullName 返回完整的 Topic 路径
 // 例如: persistent://public/default/orders
 func (tc TopicConfig) FullName() string {
-	if tc.Partition >= 0 {
-		return fmt.Sprintf("persistent://%s/%s/%s-partition-%d",
-			tc.Tenant, tc.Namespace, tc.Topic, tc.Partition)
+	domain := tc.Domain
+	if domain == "" {
+		domain = PersistentDomain
 	}
-	return fmt.Sprintf("persistent://%s/%s/%s",
-		tc.Tenant, tc.Namespace, tc.Topic)
+	return domain + "://" + tc.ShortName()
 }
 
 // ShortName 返回短名称（不带 persistent:// 前缀）
@@ -70,21 +108,26 @@ func (tc TopicConfig) NamespaceFullName() string {
 	return fmt.Sprintf("%s/%s", tc.Tenant, tc.Namespace)
 }
 
-// ParseTopic 解析 Topic 字符串
+// ParseTopic 解析 Topic 字符串，完整路径解析后可通过 FullName 还原
 // 支持以下格式:
 //   - "orders" -> public/default/orders
 //   - "tenant/namespace/topic" -> tenant/namespace/topic
 //   - "persistent://tenant/namespace/topic" -> tenant/namespace/topic
+//   - "non-persistent://tenant/namespace/topic" -> tenant/namespace/topic，Domain 为 non-persistent
+//   - "persistent://tenant/namespace/topic-partition-3" -> tenant/namespace/topic，Partition 为 3
 func ParseTopic(topic string) TopicConfig {
 	config := TopicConfig{
+		Domain:    PersistentDomain,
 		Tenant:    "public",
 		Namespace: "default",
 		Partition: -1,
 	}
 
-	// 去除 persistent:// 前缀
-	topic = strings.TrimPrefix(topic, "persistent://")
-	topic = strings.TrimPrefix(topic, "non-persistent://")
+	// 解析 persistent:// 或 non-persistent:// 前缀
+	if domain, rest, ok := strings.Cut(topic, "://"); ok {
+		config.Domain = domain
+		topic = rest
+	}
 
 	parts := strings.Split(topic, "/")
 
@@ -103,9 +146,53 @@ func ParseTopic(topic string) TopicConfig {
 		config.Topic = parts[2]
 	}
 
+	// 解析分区后缀
+	if i := strings.LastIndex(config.Topic, PartitionSuffix); i > 0 {
+		if n, err := strconv.Atoi(config.Topic[i+len(PartitionSuffix):]); err == nil && n >= 0 {
+			config.Topic = config.Topic[:i]
+			config.Partition = n
+		}
+	}
+
 	return config
 }
 
+// isPlainTopic 是否为不含 tenant 和 namespace 的 Topic 名称
+func isPlainTopic(topic string) bool {
+	return !strings.Contains(topic, "/")
+}
+
+// WithEnvironment 设置租户和环境，Send / Consume 等方法中不含 tenant 和 namespace 的 Topic 名称
+// 自动解析为 persistent://<tenant>/<环境 namespace>/<topic>，环境无效时 New 返回 ErrInvalidEnvironment
+//
+// 使用示例:
+//
+//	bus, err := New(url, WithEnvironment("nexa", kit.Staging))
+//	// 发送到 persistent://nexa/staging/orders
+//	err = bus.Send(ctx, "orders", WithPayload(data))
+func WithEnvironment(tenant string, env kit.Environment) Option {
+	return func(bus *Pulbus) {
+		bus.tenant = tenant
+		bus.environment = env
+	}
+}
+
+// ResolveTopic 解析 Topic 完整路径，未设置 WithEnvironment 或 Topic 已包含 namespace 时原样返回
+func (bus *Pulbus) ResolveTopic(topic string) string {
+	if bus.environment == "" || !isPlainTopic(topic) {
+		return topic
+	}
+	return NewTopicBuilder(bus.tenant, EnvironmentNamespace(bus.environment)).Build(topic)
+}
+
+// resolveTopicsPattern 解析正则 Topic，不含 namespace 时添加环境 namespace 前缀
+func (bus *Pulbus) resolveTopicsPattern(pattern string) string {
+	if bus.environment == "" || !isPlainTopic(pattern) {
+		return pattern
+	}
+	return regexp.QuoteMeta(NewTopicBuilder(bus.tenant, EnvironmentNamespace(bus.environment)).Build("")) + pattern
+}
+
 // TopicBuilder Topic 构建器
 type TopicBuilder struct {
 	tenant    string
@@ -136,6 +223,26 @@ func (tb *TopicBuilder) Namespace() string {
 	return fmt.Sprintf("%s/%s", tb.tenant, tb.namespace)
 }
 
+// DeadLetterTopic 返回 Topic 对应的死信 Topic，保留原 Topic 的持久化类型
+// 例如: orders -> persistent://public/default/orders-DLQ
+func DeadLetterTopic(topic string) string {
+	return letterTopic(topic, DeadLetterSuffix)
+}
+
+// RetryLetterTopic 返回 Topic 对应的重试 Topic，保留原 Topic 的持久化类型
+// 例如: orders -> persistent://public/default/orders-RETRY
+func RetryLetterTopic(topic string) string {
+	return letterTopic(topic, RetryLetterSuffix)
+}
+
+// letterTopic 返回添加后缀的非分区 Topic
+func letterTopic(topic, suffix string) string {
+	config := ParseTopic(topic)
+	config.Topic += suffix
+	config.Partition = -1
+	return config.FullName()
+}
+
 // NamespaceConfig Namespace 配置
 type NamespaceConfig struct {
 	Tenant    string
@@ -6,6 +6,10 @@ package pulbus
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"nexis.run/nexa/kit"
 )
 
 func TestDefaultTopicConfig(t *testing.T) {
@@ -205,6 +209,46 @@ func TestParseTopic(t *testing.T) {
 	}
 }
 
+func TestParseTopicRoundTrip(t *testing.T) {
+	tests := []struct {
+		input     string
+		domain    string
+		topic     string
+		partition int
+	}{
+		{"persistent://public/default/orders", PersistentDomain, "orders", -1},
+		{"non-persistent://test/app/notifications", NonPersistentDomain, "notifications", -1},
+		{"persistent://production/app/orders-partition-3", PersistentDomain, "orders", 3},
+		{"non-persistent://production/app/ticks-partition-0", NonPersistentDomain, "ticks", 0},
+		{"persistent://production/app/my-partition-key", PersistentDomain, "my-partition-key", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			config := ParseTopic(tt.input)
+			require.Equal(t, tt.domain, config.Domain)
+			require.Equal(t, tt.topic, config.Topic)
+			require.Equal(t, tt.partition, config.Partition)
+			require.Equal(t, tt.input, config.FullName())
+		})
+	}
+}
+
+func TestResolveTopic(t *testing.T) {
+	bus := &Pulbus{}
+	require.Equal(t, "orders", bus.ResolveTopic("orders"))
+
+	WithEnvironment("nexa", kit.Staging)(bus)
+	require.Equal(t, "staging", EnvironmentNamespace(kit.Staging))
+	require.Equal(t, "persistent://nexa/staging/orders", bus.ResolveTopic("orders"))
+	require.Equal(t, "public/default/orders", bus.ResolveTopic("public/default/orders"))
+	require.Equal(t, "non-persistent://nexa/staging/ticks", bus.ResolveTopic("non-persistent://nexa/staging/ticks"))
+	require.Equal(t, `persistent://nexa/staging/orders-.*`, bus.resolveTopicsPattern("orders-.*"))
+
+	_, err := New("pulsar://localhost:6650", WithEnvironment("nexa", "test"))
+	require.ErrorIs(t, err, ErrInvalidEnvironment)
+}
+
 func TestTopicBuilder(t *testing.T) {
 	builder := NewTopicBuilder("production", "app")
 
@@ -270,3 +314,35 @@ func TestNamespaceConstants(t *testing.T) {
 		})
 	}
 }
+
+func TestLetterTopics(t *testing.T) {
+	tests := []struct {
+		input string
+		dlq   string
+		retry string
+	}{
+		{"orders", "persistent://public/default/orders-DLQ", "persistent://public/default/orders-RETRY"},
+		{"persistent://production/app/events", "persistent://production/app/events-DLQ", "persistent://production/app/events-RETRY"},
+		{"non-persistent://nexa/staging/events-partition-1", "non-persistent://nexa/staging/events-DLQ", "non-persistent://nexa/staging/events-RETRY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := DeadLetterTopic(tt.input); result != tt.dlq {
+				t.Errorf("Expected '%s', got '%s'", tt.dlq, result)
+			}
+			if result := RetryLetterTopic(tt.input); result != tt.retry {
+				t.Errorf("Expected '%s', got '%s'", tt.retry, result)
+			}
+		})
+	}
+}
+
+func TestEnvironmentTenantNamespace(t *testing.T) {
+	require.Equal(t, "nexa/staging", EnvironmentTenantNamespace("nexa", kit.Staging))
+
+	// 与 WithEnvironment 解析的 Topic 使用相同的 namespace
+	bus := &Pulbus{}
+	WithEnvironment("nexa", kit.Production)(bus)
+	require.Equal(t, EnvironmentTenantNamespace("nexa", kit.Production), ParseTopic(bus.ResolveTopic("orders")).NamespaceFullName())
+}
@@ -0,0 +1,58 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// defaultPropagator 默认使用 W3C traceparent 和 baggage
+var defaultPropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// WithPropagator 设置消息属性中的链路上下文传播方式，默认使用 W3C traceparent 和 baggage
+//
+// 使用示例:
+//
+//	bus, err := New(url, WithPropagator(otel.GetTextMapPropagator()))
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(bus *Pulbus) {
+		bus.propagator = propagator
+	}
+}
+
+// textMapPropagator 获取链路上下文传播方式
+func (bus *Pulbus) textMapPropagator() propagation.TextMapPropagator {
+	if bus.propagator == nil {
+		return defaultPropagator
+	}
+	return bus.propagator
+}
+
+// inject 将 context 中的链路上下文写入消息属性
+func (bus *Pulbus) inject(ctx context.Context, msg *pulsar.ProducerMessage) {
+	if msg.Properties == nil {
+		msg.Properties = make(map[string]string)
+	}
+	bus.textMapPropagator().Inject(ctx, propagation.MapCarrier(msg.Properties))
+}
+
+// extract 从消息属性中提取链路上下文
+func (bus *Pulbus) extract(ctx context.Context, msg pulsar.Message) context.Context {
+	return bus.textMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Properties()))
+}
+
+// traceField 返回 context 中的 trace id 日志字段，无链路上下文时返回 zap.Skip
+func traceField(ctx context.Context) zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return zap.Skip()
+	}
+	return zap.String("trace_id", sc.TraceID().String())
+}
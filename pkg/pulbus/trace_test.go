@@ -0,0 +1,78 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTracePropagation(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	bus := NewMemory()
+	defer func() {
+		_ = bus.Close()
+	}()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	member, _ := baggage.NewMember("tenant", "nexa")
+	bag, _ := baggage.New(member)
+
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	type received struct {
+		traceID    trace.TraceID
+		tenant     string
+		properties map[string]string
+	}
+	ch := make(chan received, 2)
+
+	consumeCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = bus.ConsumeContext(consumeCtx, "orders", "order-sub", func(ctx context.Context, msg pulsar.Message) error {
+			ch <- received{
+				traceID:    trace.SpanContextFromContext(ctx).TraceID(),
+				tenant:     baggage.FromContext(ctx).Member("tenant").Value(),
+				properties: msg.Properties(),
+			}
+			if msg.RedeliveryCount() == 0 {
+				return errors.New("retry")
+			}
+			return nil
+		}, WithConsumerInitialPosition(pulsar.SubscriptionPositionEarliest), WithConsumerNackBackoff(0, 0))
+	}()
+
+	require.NoError(t, bus.Send(ctx, "orders", WithPayload([]byte("order"))))
+
+	r := <-ch
+	require.Equal(t, traceID, r.traceID)
+	require.Equal(t, "nexa", r.tenant)
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", r.properties["traceparent"])
+	require.Equal(t, "tenant=nexa", r.properties["baggage"])
+
+	// 处理失败的日志携带 trace id
+	<-ch
+	entries := logs.FilterMessage("[Pulsar Consumer] 消息处理失败，Nack 消息").All()
+	require.Len(t, entries, 1)
+	require.Equal(t, traceID.String(), entries[0].ContextMap()["trace_id"])
+}
@@ -0,0 +1,135 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+const defaultTransactionTimeout = time.Minute
+
+var (
+	ErrTransactionDisabled = errors.New("未启用事务，请使用 WithTransaction 配置")
+	ErrUnknownDelivery     = errors.New("消息不在处理中，无法在事务中 Ack")
+)
+
+// WithTransaction 启用事务协调器
+// timeout 为事务超时时间，超时未提交的事务由 broker 自动中止，小于等于 0 时使用默认值 1 分钟
+// 注意: 需要 broker 开启 transactionCoordinatorEnabled
+func WithTransaction(timeout time.Duration) Option {
+	return func(bus *Pulbus) {
+		if timeout <= 0 {
+			timeout = defaultTransactionTimeout
+		}
+
+		bus.clientOptions.EnableTransaction = true
+		bus.txnTimeout = timeout
+	}
+}
+
+// delivery 处理中的消息，记录消息所属的 consumer
+type delivery struct {
+	consumer *Consumer
+	acked    atomic.Bool // 是否已在事务中 ack
+}
+
+// track 记录处理中的消息，返回的函数用于结束记录
+func (bus *Pulbus) track(consumer *Consumer, msg pulsar.Message) (*delivery, func()) {
+	d := &delivery{consumer: consumer}
+	bus.deliveries.Store(msg, d)
+	return d, func() {
+		bus.deliveries.Delete(msg)
+	}
+}
+
+// Tx 事务，在事务中发送的消息和 ack 的消息一起提交或中止
+type Tx struct {
+	bus   *Pulbus
+	txn   pulsar.Transaction
+	acked []*delivery
+}
+
+// Send 在事务中发送消息，事务提交后消息才对消费者可见
+func (tx *Tx) Send(ctx context.Context, topic string, messageOpts ...ProducerOption) error {
+	return tx.bus.Send(ctx, topic, append(messageOpts, func(message *pulsar.ProducerMessage) {
+		message.Transaction = tx.txn
+	})...)
+}
+
+// Ack 在事务中 ack 消息，消息必须是当前正在处理的消息
+// 事务提交后 handler 返回时不会再次 ack 该消息
+func (tx *Tx) Ack(msg pulsar.Message) error {
+	v, ok := tx.bus.deliveries.Load(msg)
+	if !ok {
+		return ErrUnknownDelivery
+	}
+
+	d := v.(*delivery)
+	err := d.consumer.AckWithTxn(msg, tx.txn)
+	if err != nil {
+		return err
+	}
+
+	tx.acked = append(tx.acked, d)
+	return nil
+}
+
+// Transaction 在事务中执行 fn
+// fn 返回 nil 时提交事务，返回错误或 panic 时中止事务
+// 通常在 handler 中使用，实现消费-生产的精确一次处理，事务失败时 handler 应返回错误以重投消息
+//
+// 使用示例:
+//
+//	err := bus.Consume(ctx, "orders", "order-sub", func(msg pulsar.Message) error {
+//	    return bus.Transaction(ctx, func(tx *Tx) error {
+//	        if err := tx.Send(ctx, "shipments", WithPayload(payload)); err != nil {
+//	            return err
+//	        }
+//	        return tx.Ack(msg)
+//	    })
+//	})
+func (bus *Pulbus) Transaction(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	if !bus.clientOptions.EnableTransaction {
+		return ErrTransactionDisabled
+	}
+
+	var txn pulsar.Transaction
+	txn, err = bus.client.NewTransaction(bus.txnTimeout)
+	if err != nil {
+		return
+	}
+
+	tx := &Tx{bus: bus, txn: txn}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = txn.Abort(ctx)
+			panic(r)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		_ = txn.Abort(ctx)
+		return
+	}
+
+	err = txn.Commit(ctx)
+	if err != nil {
+		_ = txn.Abort(ctx)
+		return
+	}
+
+	// 提交成功后标记消息已 ack
+	for _, d := range tx.acked {
+		d.acked.Store(true)
+	}
+	return
+}
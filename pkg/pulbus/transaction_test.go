@@ -0,0 +1,86 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTransaction 记录提交和中止的事务
+type stubTransaction struct {
+	pulsar.Transaction
+
+	committed bool
+	aborted   bool
+}
+
+func (txn *stubTransaction) Commit(context.Context) error {
+	txn.committed = true
+	return nil
+}
+
+func (txn *stubTransaction) Abort(context.Context) error {
+	txn.aborted = true
+	return nil
+}
+
+type stubClient struct {
+	pulsar.Client
+
+	txn *stubTransaction
+}
+
+func (c *stubClient) NewTransaction(time.Duration) (pulsar.Transaction, error) {
+	c.txn = &stubTransaction{}
+	return c.txn, nil
+}
+
+func TestTransactionDisabled(t *testing.T) {
+	bus := &Pulbus{}
+	err := bus.Transaction(context.Background(), func(*Tx) error {
+		return nil
+	})
+	require.ErrorIs(t, err, ErrTransactionDisabled)
+}
+
+func TestTransaction(t *testing.T) {
+	client := &stubClient{}
+	bus := &Pulbus{client: client}
+	WithTransaction(0)(bus)
+	require.True(t, bus.clientOptions.EnableTransaction)
+	require.Equal(t, defaultTransactionTimeout, bus.txnTimeout)
+
+	stub := &stubConsumer{}
+	consumer := &Consumer{bus: bus, key: ConsumerKey{Topic: "orders", Subscription: "order-sub"}, Consumer: stub}
+
+	// 事务中 ack 后 handler 返回时不再 ack
+	msg := &stubMessage{}
+	consumer.handleMessage(context.Background(), msg, func(ctx context.Context, msg pulsar.Message) error {
+		return bus.Transaction(ctx, func(tx *Tx) error {
+			return tx.Ack(msg)
+		})
+	}, newConsumerOptions())
+	require.True(t, client.txn.committed)
+	require.Equal(t, int64(1), stub.acked.Load())
+
+	// 事务失败时中止
+	err := bus.Transaction(context.Background(), func(tx *Tx) error {
+		return errors.New("failed")
+	})
+	require.Error(t, err)
+	require.True(t, client.txn.aborted)
+
+	// 不在处理中的消息无法 ack
+	err = bus.Transaction(context.Background(), func(tx *Tx) error {
+		return tx.Ack(&stubMessage{})
+	})
+	require.ErrorIs(t, err, ErrUnknownDelivery)
+}
@@ -0,0 +1,109 @@
+// Copyright (C) nexa. 2026-present.
+//
+// Created at 2026-10-18, by liasica
+
+package pulbus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// DecodeError 消息解码失败
+// 解码失败的消息重投也无法成功，因此不会 Nack，而是交由 WithDecodeErrorHandler 处理
+type DecodeError struct {
+	ContentType string
+	Err         error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("消息解码失败 [%s]: %v", e.ContentType, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode 使用 codec 解码消息
+// 若消息携带的 content-type 与 codec 不一致，返回 ErrContentTypeMismatch
+func Decode[T any](codec Codec, msg pulsar.Message) (v T, err error) {
+	contentType := msg.Properties()[PropertyContentType]
+	if contentType != "" && contentType != codec.ContentType() {
+		err = &DecodeError{
+			ContentType: contentType,
+			Err:         fmt.Errorf("%w: 期望 %s", ErrContentTypeMismatch, codec.ContentType()),
+		}
+		return
+	}
+
+	err = codec.Unmarshal(msg.Payload(), newTarget(&v))
+	if err != nil {
+		err = &DecodeError{ContentType: codec.ContentType(), Err: err}
+	}
+	return
+}
+
+// newTarget 返回解码 v 时使用的目标
+// 指针类型需要先分配内存，例如 *pb.Order，解码到分配的对象；其他类型解码到 v
+func newTarget[T any](v *T) any {
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Pointer {
+		*v = reflect.New(t.Elem()).Interface().(T)
+		return *v
+	}
+	return v
+}
+
+// TypedProducer 泛型消息生产者
+//
+// 使用示例:
+//
+//	producer := NewTypedProducer[Order](bus, "orders", JSONCodec{})
+//	err := producer.Send(ctx, order, WithProducerKey(order.ID))
+type TypedProducer[T any] struct {
+	bus   Bus
+	topic string
+	codec Codec
+}
+
+// NewTypedProducer 创建泛型消息生产者
+func NewTypedProducer[T any](bus Bus, topic string, codec Codec) *TypedProducer[T] {
+	return &TypedProducer[T]{
+		bus:   bus,
+		topic: topic,
+		codec: codec,
+	}
+}
+
+// Send 编码并发送消息，同时写入 content-type 属性
+func (p *TypedProducer[T]) Send(ctx context.Context, v T, messageOpts ...ProducerOption) error {
+	b, err := p.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return p.bus.Send(ctx, p.topic, append(messageOpts, WithPayload(b), withProperty(PropertyContentType, p.codec.ContentType()))...)
+}
+
+// TypedHandler 泛型消息处理函数类型
+type TypedHandler[T any] func(msg pulsar.Message, v T) error
+
+// Subscribe 使用 codec 解码并消费消息
+// 解码失败的消息不会进入 handler，而是交由 WithDecodeErrorHandler 处理
+//
+// 使用示例:
+//
+//	err := Subscribe(ctx, bus, "orders", "order-sub", JSONCodec{}, func(msg pulsar.Message, order Order) error {
+//	    return nil
+//	})
+func Subscribe[T any](ctx context.Context, bus Bus, topic, subscription string, codec Codec, handler TypedHandler[T], opts ...ConsumerOption) error {
+	return bus.Consume(ctx, topic, subscription, func(msg pulsar.Message) error {
+		v, err := Decode[T](codec, msg)
+		if err != nil {
+			return err
+		}
+		return handler(msg, v)
+	}, opts...)
+}